@@ -1,24 +1,54 @@
 package iconik
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // JSON Object structs.
 
 type SearchCriteriaSchema struct {
-	DocTypes []string     `json:"doc_types"`
-	Filter   SearchFilter `json:"filter"`
+	DocTypes      []string     `json:"doc_types"`
+	Query         string       `json:"query,omitempty"`
+	Filter        SearchFilter `json:"filter"`
+	Sort          []SortField  `json:"sort,omitempty"`
+	IncludeFields []string     `json:"include_fields,omitempty"`
+	Facets        []string     `json:"facets,omitempty"`
 }
 
+type SortField struct {
+	Name  string    `json:"name"`
+	Order SortOrder `json:"order,omitempty"`
+}
+
+// SortOrder is the direction of a SortField.
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
 type SearchFilter struct {
-	Operator string       `json:"operator"`
-	Terms    []FilterTerm `json:"terms"`
+	Operator string         `json:"operator"`
+	Terms    []FilterTerm   `json:"terms"`
+	Filters  []SearchFilter `json:"filters,omitempty"`
 }
 
 type FilterTerm struct {
-	Name  string `json:"name"`
-	Value string `json:"value"`
+	Name    string       `json:"name"`
+	Value   string       `json:"value,omitempty"`
+	ValueIn []string     `json:"value_in,omitempty"`
+	Range   *FilterRange `json:"range,omitempty"`
+	Exists  *bool        `json:"exists,omitempty"`
+}
+
+// FilterRange bounds a term's value. An empty Min or Max leaves that end
+// open.
+type FilterRange struct {
+	Min string `json:"min,omitempty"`
+	Max string `json:"max,omitempty"`
 }
 
 type SearchResponse struct {
@@ -27,6 +57,40 @@ type SearchResponse struct {
 	Pages   int            `json:"pages"`
 	PerPage int            `json:"per_page"`
 	Total   int            `json:"total"`
+	// Facets holds the aggregations requested with SearchQuery.Facets,
+	// keyed by field name.
+	Facets map[string]Facet `json:"facets,omitempty"`
+}
+
+type Facet struct {
+	Buckets []FacetBucket `json:"buckets"`
+}
+
+// FacetBucket counts the documents sharing one value of a faceted field.
+type FacetBucket struct {
+	Key      string `json:"key"`
+	DocCount int    `json:"doc_count"`
+}
+
+// UnmarshalJSON accepts numeric and boolean keys, which Iconik returns for
+// non-text fields, as well as strings.
+func (b *FacetBucket) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Key      json.RawMessage `json:"key"`
+		DocCount int             `json:"doc_count"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	b.DocCount = raw.DocCount
+	b.Key = ""
+	if len(raw.Key) == 0 || string(raw.Key) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw.Key, &b.Key); err != nil {
+		b.Key = string(raw.Key)
+	}
+	return nil
 }
 
 type IconikObject struct {
@@ -39,6 +103,105 @@ type IconikObject struct {
 	Status        string        `json:"status"`
 }
 
+// Asset is an asset as returned by the assets API.
+type Asset struct {
+	Id                   string        `json:"id"`
+	Title                string        `json:"title"`
+	Type                 string        `json:"type"`
+	Category             string        `json:"category,omitempty"`
+	Status               string        `json:"status"`
+	ArchiveStatus        string        `json:"archive_status"`
+	AnalyzeStatus        string        `json:"analyze_status"`
+	IsOnline             bool          `json:"is_online"`
+	ExternalID           string        `json:"external_id,omitempty"`
+	ExternalLink         string        `json:"external_link,omitempty"`
+	DurationMilliseconds int64         `json:"duration_milliseconds,omitempty"`
+	CreatedByUser        string        `json:"created_by_user"`
+	UpdatedByUser        string        `json:"updated_by_user,omitempty"`
+	DateCreated          time.Time     `json:"date_created"`
+	DateModified         time.Time     `json:"date_modified"`
+	DateDeleted          *time.Time    `json:"date_deleted,omitempty"`
+	CustomKeyframe       string        `json:"custom_keyframe,omitempty"`
+	CustomPoster         string        `json:"custom_poster,omitempty"`
+	Warnings             []string      `json:"warnings,omitempty"`
+	InCollections        []string      `json:"in_collections,omitempty"`
+	Files                []IconikFile  `json:"files,omitempty"`
+	Proxies              []IconikProxy `json:"proxies,omitempty"`
+}
+
+// AssetPage is one page of a ListAssets result.
+type AssetPage struct {
+	Objects []Asset `json:"objects"`
+	Page    int     `json:"page"`
+	Pages   int     `json:"pages"`
+	PerPage int     `json:"per_page"`
+	Total   int     `json:"total"`
+}
+
+// MetadataView is a named set of metadata fields, as shown together in the
+// web UI.
+type MetadataView struct {
+	Id          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	ViewFields  []MetadataField `json:"view_fields"`
+}
+
+// MetadataField defines a custom metadata field. FieldType is one of the
+// FieldType constants.
+type MetadataField struct {
+	Name      string                `json:"name"`
+	Label     string                `json:"label"`
+	FieldType string                `json:"field_type"`
+	Multi     bool                  `json:"multi"`
+	Required  bool                  `json:"required"`
+	ReadOnly  bool                  `json:"read_only"`
+	Options   []MetadataFieldOption `json:"options,omitempty"`
+}
+
+type MetadataFieldOption struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// metadataValuesSchema is the body of an asset's metadata for one view.
+type metadataValuesSchema struct {
+	MetadataValues map[string]metadataFieldValues `json:"metadata_values"`
+}
+
+type metadataFieldValues struct {
+	FieldValues []metadataFieldValue `json:"field_values"`
+}
+
+type metadataFieldValue struct {
+	Value interface{} `json:"value"`
+}
+
+// Collection is a collection as returned by the collections API.
+type Collection struct {
+	Id            string    `json:"id"`
+	Title         string    `json:"title"`
+	ParentID      string    `json:"parent_id,omitempty"`
+	InCollections []string  `json:"in_collections,omitempty"`
+	Status        string    `json:"status"`
+	IsRoot        bool      `json:"is_root"`
+	Category      string    `json:"category,omitempty"`
+	ExternalID    string    `json:"external_id,omitempty"`
+	CreatedByUser string    `json:"created_by_user"`
+	DateCreated   time.Time `json:"date_created"`
+	DateModified  time.Time `json:"date_modified"`
+}
+
+// CollectionContentsPage is one page of a ListCollectionContents result.
+// Each object's ObjectType tells assets and sub-collections apart.
+type CollectionContentsPage struct {
+	Objects []IconikObject `json:"objects"`
+	Page    int            `json:"page"`
+	Pages   int            `json:"pages"`
+	PerPage int            `json:"per_page"`
+	Total   int            `json:"total"`
+}
+
 type CollectionResult struct {
 	Path         string `json:"path"`
 	CollectionID string `json:"collection_id"`
@@ -99,7 +262,8 @@ type NewAssetUpload struct {
 
 // IError encapsulates an error message returned by the Iconik API.
 //
-// Failures to connect to the Iconik servers, and networking problems in general can cause errors
+// Failures to connect to the Iconik servers, and networking problems in general can cause errors.
+// HTTP errors are returned as *APIError, which also matches *IError with errors.As.
 type IError struct {
 	Errors []string `json:"errors"`
 }
@@ -0,0 +1,174 @@
+package iconik
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+const (
+	assetEndpointTemplate = "assets/v1/assets/%s/"
+	assetsEndpoint        = "assets/v1/assets/"
+
+	assetArchiveEndpointTemplate = "assets/v1/assets/%s/archive/"
+	assetRestoreEndpointTemplate = "assets/v1/assets/%s/restore/"
+	purgeAssetsEndpoint          = "assets/v1/delete_queue/assets/purge/"
+
+	// assetBatchConcurrency bounds the requests GetAssets has in flight.
+	assetBatchConcurrency = 4
+)
+
+// GetAsset fetches the asset with the given ID. A missing asset is reported
+// as an error matching ErrNotFound.
+func (c *IClient) GetAsset(ctx context.Context, assetID string) (*Asset, error) {
+	endpoint := fmt.Sprintf(assetEndpointTemplate, url.PathEscape(assetID))
+	c.log().Debug("GetAsset", F("endpoint", endpoint))
+	return doJSON[Asset](ctx, c, apiCall{method: http.MethodGet, path: endpoint})
+}
+
+// GetAssets fetches several assets concurrently and returns them in the
+// order of assetIDs. It fails with the first error encountered.
+func (c *IClient) GetAssets(ctx context.Context, assetIDs []string) ([]*Asset, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	assets := make([]*Asset, len(assetIDs))
+	ids := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	workers := assetBatchConcurrency
+	if len(assetIDs) < workers {
+		workers = len(assetIDs)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ids {
+				asset, err := c.GetAsset(ctx, assetIDs[i])
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("getting asset %s: %w", assetIDs[i], err)
+						cancel()
+					})
+					continue
+				}
+				assets[i] = asset
+			}
+		}()
+	}
+feed:
+	for i := range assetIDs {
+		select {
+		case ids <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(ids)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// ListAssets returns one page of all assets visible to the caller. Pages
+// are numbered from 1; perPage below one uses the server default.
+func (c *IClient) ListAssets(ctx context.Context, page, perPage int) (*AssetPage, error) {
+	query := url.Values{}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if perPage > 0 {
+		query.Set("per_page", strconv.Itoa(perPage))
+	}
+	c.log().Debug("ListAssets", F("endpoint", assetsEndpoint), F("page", page))
+	return doJSON[AssetPage](ctx, c, apiCall{method: http.MethodGet, path: assetsEndpoint, query: query})
+}
+
+// AssetUpdate lists the changes UpdateAsset makes. Nil fields are left as
+// they are.
+type AssetUpdate struct {
+	Title      *string
+	ExternalID *string
+	Status     *string
+	// Fields holds any other asset attributes to set, keyed by their JSON
+	// name, e.g. "external_link" or "category".
+	Fields map[string]interface{}
+}
+
+func (u AssetUpdate) body() map[string]interface{} {
+	body := make(map[string]interface{}, len(u.Fields)+3)
+	for k, v := range u.Fields {
+		body[k] = v
+	}
+	if u.Title != nil {
+		body["title"] = *u.Title
+	}
+	if u.ExternalID != nil {
+		body["external_id"] = *u.ExternalID
+	}
+	if u.Status != nil {
+		body["status"] = *u.Status
+	}
+	return body
+}
+
+// UpdateAsset applies update to an asset and returns the updated asset.
+func (c *IClient) UpdateAsset(ctx context.Context, assetID string, update AssetUpdate) (*Asset, error) {
+	body := update.body()
+	if len(body) == 0 {
+		return nil, &IError{Errors: []string{"UpdateAsset: nothing to update"}}
+	}
+	endpoint := fmt.Sprintf(assetEndpointTemplate, url.PathEscape(assetID))
+	c.log().Debug("UpdateAsset", F("endpoint", endpoint))
+	return doJSON[Asset](ctx, c, apiCall{method: http.MethodPatch, path: endpoint, body: body})
+}
+
+// DeleteAsset deletes an asset. Without purge the asset is moved to the
+// delete queue, from where it can still be recovered in the web UI; with
+// purge it is then removed from the delete queue too, which cannot be
+// undone.
+func (c *IClient) DeleteAsset(ctx context.Context, assetID string, purge bool) error {
+	endpoint := fmt.Sprintf(assetEndpointTemplate, url.PathEscape(assetID))
+	c.log().Debug("DeleteAsset", F("endpoint", endpoint), F("purge", purge))
+	if _, err := c.send(ctx, apiCall{method: http.MethodDelete, path: endpoint}); err != nil {
+		return err
+	}
+	if !purge {
+		return nil
+	}
+	c.log().Debug("DeleteAsset", F("endpoint", purgeAssetsEndpoint))
+	body := map[string][]string{"ids": {assetID}}
+	if _, err := c.send(ctx, apiCall{method: http.MethodPost, path: purgeAssetsEndpoint, body: body}); err != nil {
+		return fmt.Errorf("purging asset %s: %w", assetID, err)
+	}
+	return nil
+}
+
+// ArchiveAsset moves an asset's files to archive storage.
+func (c *IClient) ArchiveAsset(ctx context.Context, assetID string) error {
+	endpoint := fmt.Sprintf(assetArchiveEndpointTemplate, url.PathEscape(assetID))
+	c.log().Debug("ArchiveAsset", F("endpoint", endpoint))
+	_, err := c.send(ctx, apiCall{method: http.MethodPost, path: endpoint})
+	return err
+}
+
+// RestoreAsset brings an archived asset's files back online.
+func (c *IClient) RestoreAsset(ctx context.Context, assetID string) error {
+	endpoint := fmt.Sprintf(assetRestoreEndpointTemplate, url.PathEscape(assetID))
+	c.log().Debug("RestoreAsset", F("endpoint", endpoint))
+	_, err := c.send(ctx, apiCall{method: http.MethodPost, path: endpoint})
+	return err
+}
@@ -0,0 +1,145 @@
+package iconik
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIClient_GetAsset(t *testing.T) {
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/assets/v1/assets/a1/" {
+			t.Errorf("GetAsset() requested %s %s; wanted GET /assets/v1/assets/a1/", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"id":"a1","title":"Lecture","type":"ASSET","status":"ACTIVE",
+			"archive_status":"NOT_ARCHIVED","analyze_status":"N/A","external_id":"ext-1",
+			"duration_milliseconds":61000,"created_by_user":"u1",
+			"date_created":"2022-03-04T05:06:07.123456+00:00","date_deleted":null,
+			"custom_keyframe":"k1","custom_poster":"p1","warnings":["NO_PROXY"]}`))
+	})
+
+	asset, err := client.GetAsset(context.Background(), "a1")
+	if err != nil {
+		t.Fatalf("GetAsset(a1) got error %v", err)
+	}
+	created := time.Date(2022, 3, 4, 5, 6, 7, 123456000, time.UTC)
+	if asset.Title != "Lecture" || asset.ExternalID != "ext-1" || asset.DurationMilliseconds != 61000 ||
+		asset.CustomKeyframe != "k1" || asset.CustomPoster != "p1" || len(asset.Warnings) != 1 ||
+		!asset.DateCreated.Equal(created) || asset.DateDeleted != nil {
+		t.Errorf("GetAsset(a1) got %+v; wanted fields decoded", asset)
+	}
+}
+
+func TestIClient_GetAssets(t *testing.T) {
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/assets/v1/assets/"), "/")
+		if id == "missing" {
+			http.Error(w, `{"errors":["not found"]}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"id":"` + id + `"}`))
+	})
+
+	ids := []string{"a", "b", "c", "d", "e", "f"}
+	assets, err := client.GetAssets(context.Background(), ids)
+	if err != nil {
+		t.Fatalf("GetAssets(%v) got error %v", ids, err)
+	}
+	for i, a := range assets {
+		if a.Id != ids[i] {
+			t.Errorf("GetAssets(%v)[%d] got %s; wanted %s", ids, i, a.Id, ids[i])
+		}
+	}
+
+	_, err = client.GetAssets(context.Background(), []string{"a", "missing", "c"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetAssets() with a missing asset got %v; wanted ErrNotFound", err)
+	}
+}
+
+func TestIClient_ListAssets(t *testing.T) {
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Encode(); got != "page=2&per_page=10" {
+			t.Errorf("ListAssets(2, 10) sent query %q", got)
+		}
+		w.Write([]byte(`{"objects":[{"id":"a"}],"page":2,"pages":3,"per_page":10,"total":21}`))
+	})
+
+	page, err := client.ListAssets(context.Background(), 2, 10)
+	if err != nil {
+		t.Fatalf("ListAssets(2, 10) got error %v", err)
+	}
+	if len(page.Objects) != 1 || page.Pages != 3 || page.Total != 21 {
+		t.Errorf("ListAssets(2, 10) got %+v", page)
+	}
+}
+
+func TestIClient_UpdateAsset(t *testing.T) {
+	var body map[string]interface{}
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/assets/v1/assets/a1/" {
+			t.Errorf("UpdateAsset() requested %s %s; wanted PATCH /assets/v1/assets/a1/", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"id":"a1","title":"New"}`))
+	})
+
+	title := "New"
+	asset, err := client.UpdateAsset(context.Background(), "a1", AssetUpdate{
+		Title:  &title,
+		Fields: map[string]interface{}{"category": "lectures"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateAsset() got error %v", err)
+	}
+	want := map[string]interface{}{"title": "New", "category": "lectures"}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("UpdateAsset() sent %v; wanted %v", body, want)
+	}
+	if asset.Title != "New" {
+		t.Errorf("UpdateAsset() got title %q; wanted %q", asset.Title, "New")
+	}
+
+	if _, err := client.UpdateAsset(context.Background(), "a1", AssetUpdate{}); err == nil {
+		t.Errorf("UpdateAsset() with no changes got nil error")
+	}
+}
+
+func TestIClient_DeleteArchiveRestoreAsset(t *testing.T) {
+	var requests []string
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(b)))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ctx := context.Background()
+
+	if err := client.DeleteAsset(ctx, "a1", false); err != nil {
+		t.Errorf("DeleteAsset(a1, false) got error %v", err)
+	}
+	if err := client.DeleteAsset(ctx, "a2", true); err != nil {
+		t.Errorf("DeleteAsset(a2, true) got error %v", err)
+	}
+	if err := client.ArchiveAsset(ctx, "a3"); err != nil {
+		t.Errorf("ArchiveAsset(a3) got error %v", err)
+	}
+	if err := client.RestoreAsset(ctx, "a3"); err != nil {
+		t.Errorf("RestoreAsset(a3) got error %v", err)
+	}
+	want := []string{
+		"DELETE /assets/v1/assets/a1/",
+		"DELETE /assets/v1/assets/a2/",
+		`POST /assets/v1/delete_queue/assets/purge/ {"ids":["a2"]}`,
+		"POST /assets/v1/assets/a3/archive/",
+		"POST /assets/v1/assets/a3/restore/",
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests got %q; wanted %q", requests, want)
+	}
+}
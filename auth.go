@@ -0,0 +1,280 @@
+package iconik
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const loginEndpoint = "auth/v1/auth/simple/login/"
+
+// Authenticator adds credentials to an API request. newRequest calls it for
+// every request, so implementations may rotate credentials at any time.
+type Authenticator interface {
+	Authenticate(ctx context.Context, req *http.Request) error
+}
+
+// clientUser is implemented by Authenticators that make requests of their
+// own. NewIClientWithOptions gives them the client's http.Client and host,
+// so they share its transport, proxy, TLS and timeout settings and talk to
+// the same Iconik instance.
+type clientUser interface {
+	useClient(hc *http.Client, host string)
+}
+
+// Invalidator is implemented by Authenticators that can obtain new
+// credentials. When Iconik answers 401, IClient calls Invalidate and sends
+// the request once more, unless NoRetry is set.
+type Invalidator interface {
+	Invalidate()
+}
+
+// setAuthHeaders sets the headers Iconik expects on authenticated requests.
+func setAuthHeaders(req *http.Request, appID, token string) {
+	req.Header.Set("App-Id", appID)
+	req.Header.Set("Auth-Token", token)
+}
+
+// authenticator returns the client's Authenticator, falling back to its
+// static Credentials.
+func (c *IClient) authenticator() Authenticator {
+	if c.Authenticator != nil {
+		return c.Authenticator
+	}
+	return TokenAuth(c.Credentials)
+}
+
+// TokenAuth authenticates with a static application token.
+type TokenAuth Credentials
+
+func (t TokenAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	setAuthHeaders(req, t.AppID, t.Token)
+	return nil
+}
+
+// EnvAuth reads the app ID and token from environment variables on every
+// request. Empty variable names default to ICONIK_APP_ID and ICONIK_TOKEN.
+type EnvAuth struct {
+	AppIDVar string
+	TokenVar string
+}
+
+func (e EnvAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	appIDVar, tokenVar := e.AppIDVar, e.TokenVar
+	if appIDVar == "" {
+		appIDVar = "ICONIK_APP_ID"
+	}
+	if tokenVar == "" {
+		tokenVar = "ICONIK_TOKEN"
+	}
+	appID, token := os.Getenv(appIDVar), os.Getenv(tokenVar)
+	if appID == "" || token == "" {
+		return fmt.Errorf("environment variables %s and %s must both be set", appIDVar, tokenVar)
+	}
+	setAuthHeaders(req, appID, token)
+	return nil
+}
+
+// FileAuth reads credentials from a JSON file of the form
+// {"app_id": "...", "token": "..."}. The file is read again whenever its
+// modification time changes, so a rotated token is picked up without a
+// restart. It is safe for concurrent use.
+type FileAuth struct {
+	Path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	creds   Credentials
+}
+
+func (f *FileAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	creds, err := f.load()
+	if err != nil {
+		return err
+	}
+	setAuthHeaders(req, creds.AppID, creds.Token)
+	return nil
+}
+
+// Invalidate forces the file to be read again on the next request.
+func (f *FileAuth) Invalidate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.modTime = time.Time{}
+}
+
+func (f *FileAuth) load() (Credentials, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if !f.modTime.IsZero() && info.ModTime().Equal(f.modTime) {
+		return f.creds, nil
+	}
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	var file struct {
+		AppID string `json:"app_id"`
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Credentials{}, fmt.Errorf("parsing credentials file %s: %w", f.Path, err)
+	}
+	if file.AppID == "" || file.Token == "" {
+		return Credentials{}, fmt.Errorf("credentials file %s needs both app_id and token", f.Path)
+	}
+	f.creds = Credentials{AppID: file.AppID, Token: file.Token}
+	f.modTime = info.ModTime()
+	return f.creds, nil
+}
+
+// PasswordAuth logs in to Iconik with a user's email and password and
+// authenticates requests with the resulting session token. The session is
+// renewed RefreshBefore ahead of its expiry, or after Iconik rejects it. It
+// is safe for concurrent use.
+type PasswordAuth struct {
+	AppID    string
+	Email    string
+	Password string
+
+	// Host is the Iconik API root. If empty, the host of the IClient the
+	// PasswordAuth was given to is used, or IconikHost outside an IClient.
+	Host string
+	// HTTPClient is used for logging in. If nil, the http.Client of the
+	// IClient the PasswordAuth was given to is used, or one with
+	// DefaultTimeout outside an IClient.
+	HTTPClient *http.Client
+	// TokenLifetime is assumed when the login response has no expiry.
+	// Zero means 30 minutes.
+	TokenLifetime time.Duration
+	// RefreshBefore is how long before expiry a new session is obtained.
+	// Zero means one minute.
+	RefreshBefore time.Duration
+
+	mu         sync.Mutex
+	token      string
+	expires    time.Time
+	httpClient *http.Client // from the IClient
+	clientHost string       // from the IClient
+}
+
+func (p *PasswordAuth) useClient(hc *http.Client, host string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.httpClient, p.clientHost = hc, host
+}
+
+func (p *PasswordAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	token, err := p.sessionToken(ctx)
+	if err != nil {
+		return err
+	}
+	setAuthHeaders(req, p.AppID, token)
+	return nil
+}
+
+// Invalidate discards the session so the next request logs in again.
+func (p *PasswordAuth) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.token = ""
+}
+
+func (p *PasswordAuth) sessionToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	refreshBefore := p.RefreshBefore
+	if refreshBefore == 0 {
+		refreshBefore = time.Minute
+	}
+	if p.token != "" && time.Until(p.expires) > refreshBefore {
+		return p.token, nil
+	}
+	token, expires, err := p.login(ctx)
+	if err != nil {
+		return "", err
+	}
+	p.token, p.expires = token, expires
+	return token, nil
+}
+
+// loginClient returns the http.Client to log in with. p.mu must be held.
+func (p *PasswordAuth) loginClient() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	if p.httpClient != nil {
+		return p.httpClient
+	}
+	return &http.Client{Timeout: DefaultTimeout}
+}
+
+func (p *PasswordAuth) login(ctx context.Context) (string, time.Time, error) {
+	host := p.Host
+	if host == "" {
+		host = p.clientHost
+	}
+	if host == "" {
+		host = IconikHost
+	} else if !strings.HasSuffix(host, "/") {
+		host = host + "/"
+	}
+	body, err := json.Marshal(map[string]string{
+		"app_id":   p.AppID,
+		"email":    p.Email,
+		"password": p.Password,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, host+loginEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.loginClient().Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", time.Time{}, apiErrorFromResponse(resp, respBody)
+	}
+	var session struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(respBody, &session); err != nil {
+		return "", time.Time{}, fmt.Errorf("decoding login response: %w", err)
+	}
+	if session.Token == "" {
+		return "", time.Time{}, errors.New("login response has no token")
+	}
+	expires := session.ExpiresAt
+	if expires.IsZero() {
+		lifetime := p.TokenLifetime
+		if lifetime == 0 {
+			lifetime = 30 * time.Minute
+		}
+		expires = time.Now().Add(lifetime)
+	}
+	return session.Token, expires, nil
+}
@@ -0,0 +1,153 @@
+package iconik
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPasswordAuth_LoginAndRenew(t *testing.T) {
+	var logins int32
+	validToken := "session-1"
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/"+loginEndpoint {
+			var body map[string]string
+			json.NewDecoder(req.Body).Decode(&body)
+			if body["email"] != "user@test.com" || body["password"] != "pw" || body["app_id"] != "testAppID" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			n := atomic.AddInt32(&logins, 1)
+			json.NewEncoder(rw).Encode(map[string]string{"token": fmt.Sprintf("session-%d", n)})
+			return
+		}
+		if req.Header.Get("Auth-Token") != validToken || req.Header.Get("App-Id") != "testAppID" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(rw).Encode(GetResponse{Objects: []Object{{URL: "https://test.com/url"}}})
+	}))
+	defer server.Close()
+
+	auth := &PasswordAuth{AppID: "testAppID", Email: "user@test.com", Password: "pw", Host: server.URL}
+	client := newTestClient(t, server.URL, WithAuthenticator(auth))
+	for i := 0; i < 2; i++ {
+		if _, err := client.GenerateSignedProxyUrl("asset"); err != nil {
+			t.Fatalf("GenerateSignedProxyUrl() got %v; wanted no error", err)
+		}
+	}
+	if logins != 1 {
+		t.Errorf("logged in %d times; wanted the session to be reused", logins)
+	}
+
+	// The server rotates the session; the client should log in again.
+	validToken = "session-2"
+	if _, err := client.GenerateSignedProxyUrl("asset"); err != nil {
+		t.Fatalf("GenerateSignedProxyUrl() after rotation got %v; wanted no error", err)
+	}
+	if logins != 2 {
+		t.Errorf("logged in %d times; wanted a second login after a 401", logins)
+	}
+
+	// With NoRetry a rejected session is reported instead.
+	validToken = "session-9"
+	client.NoRetry = true
+	if _, err := client.GenerateSignedProxyUrl("asset"); err == nil {
+		t.Errorf("GenerateSignedProxyUrl() with NoRetry got no error; wanted ErrUnauthorized")
+	}
+}
+
+func TestPasswordAuth_RefreshBeforeExpiry(t *testing.T) {
+	var logins int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&logins, 1)
+		json.NewEncoder(rw).Encode(map[string]interface{}{
+			"token":      "session",
+			"expires_at": time.Now().Add(30 * time.Second),
+		})
+	}))
+	defer server.Close()
+
+	auth := &PasswordAuth{AppID: "a", Email: "e", Password: "p", Host: server.URL}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for i := 0; i < 2; i++ {
+		if err := auth.Authenticate(req.Context(), req); err != nil {
+			t.Fatalf("Authenticate() got %v; wanted no error", err)
+		}
+	}
+	// The session expires within RefreshBefore, so every request renews it.
+	if logins != 2 {
+		t.Errorf("logged in %d times; wanted 2", logins)
+	}
+}
+
+func TestPasswordAuth_UsesClientTransport(t *testing.T) {
+	var paths []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		paths = append(paths, req.URL.Host+req.URL.Path)
+		if req.URL.Path == "/API/"+loginEndpoint {
+			return jsonResponse(http.StatusOK, map[string]string{"token": "session"}), nil
+		}
+		return jsonResponse(http.StatusOK, GetResponse{Objects: []Object{{URL: "https://test.com/url"}}}), nil
+	})
+	auth := &PasswordAuth{AppID: "a", Email: "e", Password: "p"}
+	client := newTestClient(t, "https://iconik.test/API/", WithTransport(transport), WithAuthenticator(auth))
+	if _, err := client.GenerateSignedProxyUrl("asset"); err != nil {
+		t.Fatalf("GenerateSignedProxyUrl() got %v; wanted no error", err)
+	}
+	if len(paths) != 2 || paths[0] != "iconik.test/API/"+loginEndpoint {
+		t.Errorf("transport got requests %v; wanted the login on the client's host and then the API call", paths)
+	}
+}
+
+func TestFileAuth_PicksUpRotatedToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds.json")
+	write := func(token string, mtime time.Time) {
+		if err := os.WriteFile(path, []byte(`{"app_id": "app", "token": "`+token+`"}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, mtime, mtime)
+	}
+	write("first", time.Now().Add(-time.Hour))
+	auth := &FileAuth{Path: path}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if err := auth.Authenticate(req.Context(), req); err != nil {
+		t.Fatalf("Authenticate() got %v; wanted no error", err)
+	}
+	if got := req.Header.Get("Auth-Token"); got != "first" {
+		t.Errorf("Auth-Token got %q; wanted %q", got, "first")
+	}
+
+	write("second", time.Now())
+	if err := auth.Authenticate(req.Context(), req); err != nil {
+		t.Fatalf("Authenticate() got %v; wanted no error", err)
+	}
+	if got := req.Header.Get("Auth-Token"); got != "second" {
+		t.Errorf("Auth-Token after rotation got %q; wanted %q", got, "second")
+	}
+}
+
+func TestEnvAuth_Authenticate(t *testing.T) {
+	t.Setenv("TEST_ICONIK_APP", "envApp")
+	t.Setenv("TEST_ICONIK_TOKEN", "envToken")
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	auth := EnvAuth{AppIDVar: "TEST_ICONIK_APP", TokenVar: "TEST_ICONIK_TOKEN"}
+	if err := auth.Authenticate(req.Context(), req); err != nil {
+		t.Fatalf("Authenticate() got %v; wanted no error", err)
+	}
+	if req.Header.Get("App-Id") != "envApp" || req.Header.Get("Auth-Token") != "envToken" {
+		t.Errorf("Authenticate() set headers %v; wanted the environment's credentials", req.Header)
+	}
+
+	t.Setenv("TEST_ICONIK_TOKEN", "")
+	if err := auth.Authenticate(req.Context(), req); err == nil {
+		t.Errorf("Authenticate() with an empty token got no error; wanted one")
+	}
+}
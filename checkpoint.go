@@ -0,0 +1,300 @@
+package iconik
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultCheckpointMaxAge is how long an upload checkpoint stays usable by
+// default. Upload URLs and tokens do not outlive it by much.
+const DefaultCheckpointMaxAge = 24 * time.Hour
+
+// checkpointVersion is bumped whenever uploadCheckpoint changes
+// incompatibly.
+const checkpointVersion = 1
+
+// checkpointHeadSize is how much of the start of the file is hashed to
+// tell whether a checkpoint belongs to it.
+const checkpointHeadSize = 1024 * 1024
+
+// ErrStaleCheckpoint is matched by errors.Is when ResumeUpload finds that a
+// checkpoint can no longer be resumed: it is too old, the file has changed,
+// or its asset is gone. ResumeUpload has then already deleted the asset,
+// failed the job and removed the checkpoint, so the file can be uploaded
+// afresh.
+var ErrStaleCheckpoint = errors.New("iconik: stale upload checkpoint")
+
+// uploadCheckpoint is what a checkpoint file holds.
+type uploadCheckpoint struct {
+	Version  int            `json:"version"`
+	Upload   NewAssetUpload `json:"upload"`
+	FileSize int64          `json:"file_size"`
+	PartSize int64          `json:"part_size"`
+	HeadSha1 string         `json:"head_sha1"`
+	// Parts maps the numbers of the parts sent so far to their SHA1s.
+	Parts map[int]string `json:"parts,omitempty"`
+	// Transferred is set once all of the file is in storage, so only
+	// FinishUpload is left to do.
+	Transferred bool `json:"transferred,omitempty"`
+	// Finished records the steps of FinishUpload already done.
+	Finished finishState `json:"finished"`
+	Created  time.Time   `json:"created"`
+	Updated  time.Time   `json:"updated"`
+}
+
+// checkpointFile is a checkpoint being kept up to date during an upload.
+// It is safe for concurrent use.
+type checkpointFile struct {
+	path string
+	mu   sync.Mutex
+	cp   uploadCheckpoint
+}
+
+// newCheckpointFile saves the first checkpoint of NAU's upload.
+func newCheckpointFile(path string, NAU *NewAssetUpload, r io.ReaderAt, size, partSize int64) (*checkpointFile, error) {
+	head, err := headSha1(r, size)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	f := &checkpointFile{path: path, cp: uploadCheckpoint{
+		Version:  checkpointVersion,
+		Upload:   *NAU,
+		FileSize: size,
+		PartSize: partSize,
+		HeadSha1: head,
+		Parts:    map[int]string{},
+		Created:  now,
+		Updated:  now,
+	}}
+	if err := f.save(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// loadCheckpointFile reads the checkpoint at path.
+func loadCheckpointFile(path string) (*checkpointFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &checkpointFile{path: path}
+	if err := json.Unmarshal(data, &f.cp); err != nil {
+		return nil, fmt.Errorf("reading checkpoint %s: %w", path, err)
+	}
+	if f.cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("checkpoint %s has version %d; wanted %d", path, f.cp.Version, checkpointVersion)
+	}
+	if f.cp.Parts == nil {
+		f.cp.Parts = map[int]string{}
+	}
+	return f, nil
+}
+
+// part returns the SHA1 of a part already sent, or "" if it has not been.
+func (f *checkpointFile) part(partNum int) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cp.Parts[partNum]
+}
+
+// partDone records that a part has been sent.
+func (f *checkpointFile) partDone(partNum int, sha string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cp.Parts[partNum] = sha
+	return f.saveLocked()
+}
+
+func (f *checkpointFile) transferred() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cp.Transferred
+}
+
+// markTransferred records that all of the file has been sent, with the
+// part SHA1s FinishUpload needs.
+func (f *checkpointFile) markTransferred(sha1List []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cp.Upload.Sha1List = sha1List
+	f.cp.Transferred = true
+	return f.saveLocked()
+}
+
+// markFinished records the steps of FinishUpload done so far.
+func (f *checkpointFile) markFinished(state finishState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cp.Finished = state
+	return f.saveLocked()
+}
+
+func (f *checkpointFile) save() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.saveLocked()
+}
+
+// saveLocked writes the checkpoint to a temporary file and renames it over
+// the old one, so a crash never leaves a partly written checkpoint. f.mu
+// must be held.
+func (f *checkpointFile) saveLocked() error {
+	f.cp.Updated = time.Now()
+	data, err := json.MarshalIndent(f.cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+func (f *checkpointFile) remove() error {
+	err := os.Remove(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// headSha1 hashes the start of the file, which is enough to notice when a
+// checkpoint is used with the wrong file without reading all of it.
+func headSha1(r io.ReaderAt, size int64) (string, error) {
+	n := int64(checkpointHeadSize)
+	if size < n {
+		n = size
+	}
+	h := sha1.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, n)); err != nil {
+		return "", fmt.Errorf("reading file header: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ResumeUpload finishes an upload that UploadFile started with
+// opts.CheckpointPath set but did not complete. r and size must be the same
+// file as before. Parts already sent are skipped; a single-part upload is
+// sent again in full, and steps of finishing the upload already done are not
+// repeated. Of opts, only CheckpointPath, CheckpointMaxAge,
+// Concurrency, Progress and ProgressInterval are used. It returns the ID of
+// the asset.
+//
+// If the checkpoint is stale, the abandoned asset and job are cleaned up and
+// the error matches ErrStaleCheckpoint.
+func (c *IClient) ResumeUpload(ctx context.Context, r io.ReaderAt, size int64, opts UploadOptions) (string, error) {
+	if opts.CheckpointPath == "" {
+		return "", &IError{Errors: []string{"ResumeUpload: CheckpointPath is required"}}
+	}
+	f, err := loadCheckpointFile(opts.CheckpointPath)
+	if err != nil {
+		return "", err
+	}
+	maxAge := opts.CheckpointMaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultCheckpointMaxAge
+	}
+	NAU := f.cp.Upload
+	if reason, err := c.checkpointStale(ctx, f, r, size, maxAge); err != nil {
+		return "", err
+	} else if reason != "" {
+		c.log().Info("abandoning stale upload", F("asset_id", NAU.AssetID), F("job_id", NAU.JobID), F("reason", reason))
+		if err := c.abandonUpload(ctx, &NAU, "FAILED"); err != nil {
+			return "", fmt.Errorf("cleaning up stale upload of asset %s: %w", NAU.AssetID, err)
+		}
+		if err := f.remove(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: %s", ErrStaleCheckpoint, reason)
+	}
+
+	c.log().Info("resuming upload", F("asset_id", NAU.AssetID), F("parts_done", len(f.cp.Parts)))
+	u := &upload{c: c, NAU: &NAU, r: r, size: size, partSize: f.cp.PartSize, concurrency: opts.Concurrency, resumed: true, checkpoint: f,
+		finished: f.cp.Finished, onProgress: opts.Progress, progressInterval: opts.ProgressInterval}
+	return u.run(ctx)
+}
+
+// checkpointStale returns why f can no longer be resumed, or "" if it can.
+func (c *IClient) checkpointStale(ctx context.Context, f *checkpointFile, r io.ReaderAt, size int64, maxAge time.Duration) (string, error) {
+	if age := time.Since(f.cp.Updated); age > maxAge {
+		return fmt.Sprintf("checkpoint is %s old", age.Round(time.Second)), nil
+	}
+	if size != f.cp.FileSize {
+		return fmt.Sprintf("file is %d bytes; checkpoint is for %d", size, f.cp.FileSize), nil
+	}
+	head, err := headSha1(r, size)
+	if err != nil {
+		return "", err
+	}
+	if head != f.cp.HeadSha1 {
+		return "file has changed since the checkpoint", nil
+	}
+	asset, err := c.GetAsset(ctx, f.cp.Upload.AssetID)
+	if errors.Is(err, ErrNotFound) {
+		return "asset no longer exists", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if asset.Status == "DELETED" {
+		return "asset has been deleted", nil
+	}
+	return "", nil
+}
+
+// cleanupTimeout bounds the requests that clean up after a failed upload,
+// which cannot use the context if it was cancelled.
+const cleanupTimeout = 30 * time.Second
+
+// discardUpload abandons an upload that failed under ctx: its job is
+// aborted if ctx was cancelled and failed otherwise. Cleanup failures are
+// only logged, since the upload's own error is what the caller needs.
+func (c *IClient) discardUpload(ctx context.Context, NAU *NewAssetUpload) {
+	status := "FAILED"
+	if ctx.Err() != nil {
+		status = "ABORTED"
+	}
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if err := c.abandonUpload(cleanupCtx, NAU, status); err != nil {
+		c.log().Warn("cleaning up failed upload", F("asset_id", NAU.AssetID), F("error", err))
+	}
+}
+
+// abandonUpload deletes the asset of an upload that will not be finished
+// and gives its job the final status, FAILED or ABORTED.
+func (c *IClient) abandonUpload(ctx context.Context, NAU *NewAssetUpload, status string) error {
+	if err := c.DeleteAsset(ctx, NAU.AssetID, false); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if NAU.JobID == "" {
+		return nil
+	}
+	endpoint := fmt.Sprintf(patchJobCompleteEndpointTemplate, NAU.JobID)
+	body := map[string]string{"status": status}
+	if _, err := c.send(ctx, apiCall{method: http.MethodPatch, path: endpoint, body: body}); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package iconik
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failedCheckpointedUpload starts an upload of content that fails at part
+// 2, leaving a checkpoint at the returned path.
+func failedCheckpointedUpload(t *testing.T, s *fakeUploadServer, client *IClient, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "upload.checkpoint")
+	s.failPart = 2
+	_, err := client.UploadFile(context.Background(), bytes.NewReader(content), int64(len(content)), UploadOptions{
+		CollectionID:   "c",
+		FileName:       "f",
+		PartSize:       MinPartSize,
+		Concurrency:    1,
+		CheckpointPath: path,
+	})
+	if err == nil {
+		t.Fatalf("UploadFile() succeeded; wanted part 2 to fail")
+	}
+	s.failPart = 0
+	return path
+}
+
+func TestIClient_ResumeUpload(t *testing.T) {
+	s := newFakeUploadServer(t)
+	client := newTestClient(t, s.URL)
+
+	content := make([]byte, 2*MinPartSize+100)
+	for i := range content {
+		content[i] = byte(i * 3)
+	}
+	path := failedCheckpointedUpload(t, s, client, content)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("UploadFile() left no checkpoint: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("checkpoint has permissions %v; wanted 0600", perm)
+	}
+	var cp uploadCheckpoint
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &cp); err != nil {
+		t.Fatalf("checkpoint is not JSON: %v", err)
+	}
+	if len(cp.Parts) != 1 || cp.Parts[1] == "" || cp.Upload.AssetID == "" {
+		t.Errorf("checkpoint got parts %v for asset %q; wanted part 1 of the asset", cp.Parts, cp.Upload.AssetID)
+	}
+
+	_, err = client.UploadFile(context.Background(), bytes.NewReader(content), int64(len(content)), UploadOptions{
+		CollectionID:   "c",
+		FileName:       "f",
+		CheckpointPath: path,
+	})
+	if err == nil {
+		t.Errorf("UploadFile() overwrote an existing checkpoint")
+	}
+
+	s.parts = nil
+	assetID, err := client.ResumeUpload(context.Background(), bytes.NewReader(content), int64(len(content)), UploadOptions{CheckpointPath: path})
+	if err != nil {
+		t.Fatalf("ResumeUpload() got error %v", err)
+	}
+	if assetID != cp.Upload.AssetID {
+		t.Errorf("ResumeUpload() got asset %q; wanted %q", assetID, cp.Upload.AssetID)
+	}
+	var sent []string
+	for _, p := range s.parts {
+		sent = append(sent, p.header.Get("X-Bz-Part-Number"))
+	}
+	sort.Strings(sent)
+	if strings.Join(sent, ",") != "2,3" {
+		t.Errorf("ResumeUpload() sent parts %v; wanted 2 and 3", sent)
+	}
+	var finish struct {
+		Sha1List []string `json:"sha1_list"`
+	}
+	for i, c := range s.calls {
+		if strings.HasSuffix(c, "/multipart/b2/finish/") {
+			json.Unmarshal(s.bodies[i], &finish)
+		}
+	}
+	if len(finish.Sha1List) != 3 || finish.Sha1List[0] != cp.Parts[1] {
+		t.Errorf("ResumeUpload() finished with sha1_list %v; wanted all 3 parts starting with %s", finish.Sha1List, cp.Parts[1])
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("ResumeUpload() left the checkpoint behind")
+	}
+}
+
+func TestIClient_ResumeUploadSkipsFinishedSteps(t *testing.T) {
+	s := newFakeUploadServer(t)
+	client := newTestClient(t, s.URL)
+	path := filepath.Join(t.TempDir(), "upload.checkpoint")
+	content := make([]byte, MinPartSize+100)
+
+	s.failCall = "keyframes"
+	_, err := client.UploadFile(context.Background(), bytes.NewReader(content), int64(len(content)), UploadOptions{
+		CollectionID:   "c",
+		FileName:       "f",
+		PartSize:       MinPartSize,
+		CheckpointPath: path,
+	})
+	if err == nil {
+		t.Fatalf("UploadFile() succeeded; wanted the keyframe request to fail")
+	}
+
+	s.failCall = ""
+	s.calls, s.bodies, s.parts = nil, nil, nil
+	if _, err := client.ResumeUpload(context.Background(), bytes.NewReader(content), int64(len(content)), UploadOptions{CheckpointPath: path}); err != nil {
+		t.Fatalf("ResumeUpload() got error %v", err)
+	}
+	if len(s.parts) != 0 || s.called("multipart/b2/finish") || s.called("PATCH /files/v1/assets/id-assets-v1-assets/files/") {
+		t.Errorf("ResumeUpload() got calls %v and %d parts; wanted only the steps after closing the file", s.calls, len(s.parts))
+	}
+	if !s.called("keyframes") || !s.called("PATCH /jobs/v1/jobs/") {
+		t.Errorf("ResumeUpload() got calls %v; wanted keyframes requested and the job finished", s.calls)
+	}
+}
+
+func TestIClient_ResumeUploadStale(t *testing.T) {
+	content := make([]byte, 2*MinPartSize+100)
+	changed := append([]byte{1}, content[1:]...)
+	tests := []struct {
+		name    string
+		content []byte
+		maxAge  time.Duration
+	}{
+		{"changed file", changed, 0},
+		{"too old", content, time.Nanosecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeUploadServer(t)
+			client := newTestClient(t, s.URL)
+			path := failedCheckpointedUpload(t, s, client, content)
+
+			s.parts = nil
+			_, err := client.ResumeUpload(context.Background(), bytes.NewReader(tt.content), int64(len(tt.content)), UploadOptions{
+				CheckpointPath:   path,
+				CheckpointMaxAge: tt.maxAge,
+			})
+			if !errors.Is(err, ErrStaleCheckpoint) {
+				t.Errorf("ResumeUpload() got %v; wanted ErrStaleCheckpoint", err)
+			}
+			if len(s.parts) != 0 {
+				t.Errorf("ResumeUpload() sent %d parts of a stale upload", len(s.parts))
+			}
+			if !s.called("DELETE /assets/v1/assets/") || !s.called("PATCH /jobs/v1/jobs/") {
+				t.Errorf("ResumeUpload() got calls %v; wanted the asset deleted and the job failed", s.calls)
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("ResumeUpload() left the stale checkpoint behind")
+			}
+		})
+	}
+}
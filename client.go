@@ -1,14 +1,17 @@
 // Package iconik API for Golang
+//
+// Methods that existed before the client supported contexts come in pairs:
+// Foo uses context.Background() and FooContext takes a ctx. Methods added
+// since take a ctx as their first argument and have no context-free form.
 package iconik
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -33,6 +36,14 @@ const (
 	keyframeGenerateEndpointTemplate  = "files/v1/assets/%s/files/%s/keyframes/"
 	patchJobCompleteEndpointTemplate  = "jobs/v1/jobs/%s"
 	createCollectionEndpoint          = "assets/v1/collections/"
+
+	// maxCollectionDepth bounds how many parents GetCollectionIDs follows,
+	// so a corrupted parent chain can't recurse forever.
+	maxCollectionDepth = 100
+
+	// collectionLookupConcurrency bounds the ancestor chains
+	// GetCollectionIDs resolves at once.
+	collectionLookupConcurrency = 8
 )
 
 // Credentials are the identification required by the Iconik API
@@ -51,152 +62,84 @@ type Credentials struct {
 type IClient struct {
 	Credentials
 
-	// If true, don't retry requests if authorization has expired
+	// If true, never retry failed requests, regardless of RetryPolicy,
+	// and don't retry requests if authorization has expired
 	NoRetry bool
 
-	// If true, display debugging information about API calls
+	// Authenticator adds credentials to every request. If nil, the static
+	// Credentials are used.
+	Authenticator Authenticator
+
+	// RetryPolicy controls how transient failures are retried. If nil,
+	// DefaultRetryPolicy is used.
+	RetryPolicy *RetryPolicy
+
+	// RateLimits throttles requests per endpoint family to stay within
+	// Iconik's API quotas. If nil, requests are not throttled.
+	RateLimits *RateLimits
+
+	// CollectionCache remembers collections looked up while resolving
+	// collection paths, across calls. If nil, each call starts with an
+	// empty cache.
+	CollectionCache *CollectionCache
+
+	// If true and no Logger was configured, write debugging information
+	// about API calls to the standard logger
 	Debug bool
 
 	// State
 	host       string
-	httpClient http.Client
+	httpClient *http.Client
+	userAgent  string
+	logger     Logger
 }
 
 // NewIClient creates a new Client for accessing the Iconik API.
 func NewIClient(creds Credentials, host string, debug bool) (*IClient, error) {
-	if host == "" {
-		host = IconikHost
-	} else if !strings.HasSuffix(host, "/") {
-		host = host + "/"
-	}
-	c := &IClient{
-		Credentials: creds,
-		host:        host,
-		Debug:       debug,
-	}
+	return NewIClientWithOptions(creds, host, WithDebug(debug))
+}
 
-	return c, nil
+// httpDoer returns the http.Client used for API requests.
+func (c *IClient) httpDoer() *http.Client {
+	if c.httpClient == nil {
+		return http.DefaultClient
+	}
+	return c.httpClient
 }
 
-// Create an authorized request using the client's credentials
-func (c *IClient) newRequest(method, apiPath string, body io.Reader, headerSettings http.Header) (*http.Request, error) {
+// Create an authorized request using the client's Authenticator
+func (c *IClient) newRequest(ctx context.Context, method, apiPath string, body io.Reader, headerSettings http.Header) (*http.Request, error) {
 	path := c.host + apiPath
 	header := make(http.Header)
-	header.Add("App-Id", c.AppID)
-	header.Add("Auth-Token", c.Token)
 	header.Add("accept", "application/json")
 	header.Add("Content-Type", "application/json")
+	if c.userAgent != "" {
+		header.Add("User-Agent", c.userAgent)
+	}
 	for k, vs := range headerSettings {
 		for _, value := range vs {
-			if c.Debug {
-				log.Printf("Adding (%s, %s) to header\n", k, value)
-			}
 			header.Add(k, value)
 		}
 	}
 
-	req, err := http.NewRequest(method, path, body)
+	req, err := http.NewRequestWithContext(ctx, method, path, body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header = header
-
-	if err != nil {
-		return nil, err
+	if err := c.authenticator().Authenticate(ctx, req); err != nil {
+		return nil, fmt.Errorf("authenticating request: %w", err)
 	}
 
-	if c.Debug {
-		log.Printf("newRequest: %s %s\n", method, req.URL)
-	}
+	c.log().Debug("newRequest", F("method", method), F("url", req.URL.String()), F("header", header))
 
 	return req, nil
 }
 
-// Dispatch an authorized API GET request
-func (c *IClient) get(apiPath string, body io.Reader, header http.Header) (*http.Response, error) {
-	req, err := c.newRequest(http.MethodGet, apiPath, body, header)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := c.httpClient.Do(req)
-	return resp, err
-}
-
-// Dispatch an authorized API POST request
-func (c *IClient) post(apiPath string, body io.Reader, header http.Header) (*http.Response, error) {
-	req, err := c.newRequest(http.MethodPost, apiPath, body, header)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.httpClient.Do(req)
-	return resp, err
-}
-
-// Dispatch an authorized API PATCH request
-func (c *IClient) patch(apiPath string, body io.Reader, header http.Header) (*http.Response, error) {
-	req, err := c.newRequest(http.MethodPatch, apiPath, body, header)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.httpClient.Do(req)
-	return resp, err
-}
-
-// Attempts to parse a response body
-func (c *IClient) parseSearchResponse(resp *http.Response) (*SearchResponse, error) {
-	response := SearchResponse{}
-	defer resp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	if c.Debug {
-		log.Printf("Response: %s", body)
-	}
-
-	// Check response code
-	switch resp.StatusCode {
-	case 200: // Response is OK
-	default:
-		return nil, iErrorFromBody(resp.StatusCode, body)
-	}
-	err = json.Unmarshal(body, &response)
-	return &response, err
-}
-
-func (c *IClient) parseUrlResponse(resp *http.Response) (string, error) {
-	response := GetResponse{}
-	defer resp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	if c.Debug {
-		log.Printf("Response: %s", body)
-	}
-
-	// Check response code
-	switch resp.StatusCode {
-	case 200: // Response is OK
-	default:
-		return "", iErrorFromBody(resp.StatusCode, body)
-	}
-
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return "", err
-	}
-
+// uniqueURL returns the only non-empty URL among objects.
+func uniqueURL(objects []Object) (string, error) {
 	retVal := ""
-	for _, v := range response.Objects {
+	for _, v := range objects {
 		if v.URL != "" {
 			if retVal != "" {
 				return "", fmt.Errorf("more than one URL in response")
@@ -204,39 +147,9 @@ func (c *IClient) parseUrlResponse(resp *http.Response) (string, error) {
 			retVal = v.URL
 		}
 	}
-
 	return retVal, nil
 }
 
-func (c *IClient) parseObjectResponse(resp *http.Response) (string, error) {
-	response := Object{}
-	defer resp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	if c.Debug {
-		log.Printf("Response: %s", body)
-	}
-
-	// Check response code
-	switch resp.StatusCode {
-	case 200: // Response is OK
-	default:
-		return "", iErrorFromBody(resp.StatusCode, body)
-	}
-
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return "", err
-	}
-
-	return response.URL, nil
-}
-
 // escapeLucene escapes characters that have special meaning in Lucene query
 // syntax so they are treated as literals. Without this, titles containing
 // characters like '?' or '(' cause the query parser to mis-interpret the
@@ -275,64 +188,31 @@ func makeProxyUrlBody() ProxyGetUrlSchema {
 	return ProxyGetUrlSchema{}
 }
 
-// iErrorFromBody parses an Iconik API error response body.
-// If the body cannot be parsed as {"errors": [...]} or the list is empty,
-// it falls back to including the raw HTTP status and body text so the caller
-// always sees what Iconik actually returned.
-func iErrorFromBody(statusCode int, body []byte) error {
-	iErr := &IError{}
-	if err := json.Unmarshal(body, iErr); err != nil || len(iErr.Errors) == 0 {
-		return &IError{Errors: []string{fmt.Sprintf("HTTP %d: %s", statusCode, string(body))}}
-	}
-	return iErr
-}
-
 // SearchWithTag performs an Iconik API Search for assets with the matching tag.
 // Args:
 // apiPath: The API Resource
 // tag: The metadata tag on Iconik, eg. "TeachingVideos," that you want to find matching assets for.
 // response: The response object to be filled out.
 func (c *IClient) SearchWithTag(tag string, isCollection bool) (*SearchResponse, error) {
-	return c.SearchWithTitleAndTag("", tag, isCollection)
+	return c.SearchWithTagContext(context.Background(), tag, isCollection)
+}
+
+// SearchWithTagContext is like SearchWithTag but uses ctx for every request it makes.
+func (c *IClient) SearchWithTagContext(ctx context.Context, tag string, isCollection bool) (*SearchResponse, error) {
+	return c.SearchWithTitleAndTagContext(ctx, "", tag, isCollection)
 }
 
 func (c *IClient) GetKeyframeUrl(assetID string) (string, error) {
+	return c.GetKeyframeUrlContext(context.Background(), assetID)
+}
+
+// GetKeyframeUrlContext is like GetKeyframeUrl but uses ctx for every request it makes.
+func (c *IClient) GetKeyframeUrlContext(ctx context.Context, assetID string) (string, error) {
 	header := make(http.Header)
 	header.Add("asset_id", assetID)
 	keyframeEndpoint := fmt.Sprintf(keyframeEndpointTemplate, assetID)
-	if c.Debug {
-		log.Println("----")
-		log.Printf("GetKeyframeUrl: %s %v", keyframeEndpoint, header)
-	}
-	resp, err := c.get(keyframeEndpoint, nil, header)
-	if err != nil {
-		if c.Debug {
-			log.Printf("IClient.get(%s) returned an error: %v\n", keyframeEndpoint, err)
-		}
-		return "", err
-	}
-
-	response := GetResponse{}
-	defer resp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	if c.Debug {
-		log.Printf("Response: %s", body)
-	}
-
-	// Check response code
-	switch resp.StatusCode {
-	case 200: // Response is OK
-	default:
-		return "", iErrorFromBody(resp.StatusCode, body)
-	}
-
-	err = json.Unmarshal(body, &response)
+	c.log().Debug("GetKeyframeUrl", F("endpoint", keyframeEndpoint))
+	response, err := doJSON[GetResponse](ctx, c, apiCall{method: http.MethodGet, path: keyframeEndpoint, header: header})
 	if err != nil {
 		return "", err
 	}
@@ -348,347 +228,205 @@ func (c *IClient) GetKeyframeUrl(assetID string) (string, error) {
 
 // New Function Search With Title:
 func (c *IClient) SearchWithTitleAndTag(title string, tag string, isCollection bool) (*SearchResponse, error) {
-	request := makeSearchBody(title, tag, isCollection)
-	body, err := json.Marshal(request)
-	if err != nil {
-		return &SearchResponse{}, err
-	}
-
-	var allObjects []IconikObject
-	page := 1
-	for {
-		endpoint := fmt.Sprintf("%s?page=%d&per_page=100", searchEndpoint, page)
-		if c.Debug {
-			log.Println("----")
-			log.Printf("SearchWithTitleAndTag: %s %s", endpoint, body)
-		}
-		resp, err := c.post(endpoint, bytes.NewReader(body), http.Header{})
-		if err != nil {
-			if c.Debug {
-				log.Printf("IClient.post(%s, %v) returned an error: %v\n", endpoint, body, err)
-			}
-			return &SearchResponse{}, err
-		}
-
-		pageResp, err := c.parseSearchResponse(resp)
-		if err != nil {
-			return &SearchResponse{}, err
-		}
-
-		allObjects = append(allObjects, pageResp.Objects...)
-
-		if page >= pageResp.Pages || len(pageResp.Objects) == 0 {
-			break
-		}
-		page++
-	}
+	return c.SearchWithTitleAndTagContext(context.Background(), title, tag, isCollection)
+}
 
-	return &SearchResponse{
-		Objects: allObjects,
-		Total:   len(allObjects),
-	}, nil
+// SearchWithTitleAndTagContext is like SearchWithTitleAndTag but uses ctx for every request it makes.
+func (c *IClient) SearchWithTitleAndTagContext(ctx context.Context, title string, tag string, isCollection bool) (*SearchResponse, error) {
+	return c.searchAll(c.newSearchIterator(ctx, makeSearchBody(title, tag, isCollection), 0, 0))
 }
 
 func (c *IClient) GenerateSignedProxyUrl(assetID string) (string, error) {
+	return c.GenerateSignedProxyUrlContext(context.Background(), assetID)
+}
+
+// GenerateSignedProxyUrlContext is like GenerateSignedProxyUrl but uses ctx for every request it makes.
+func (c *IClient) GenerateSignedProxyUrlContext(ctx context.Context, assetID string) (string, error) {
 	header := make(http.Header)
 	header.Add("asset_id", assetID)
 	proxyEndpoint := fmt.Sprintf(proxyEndpointTemplate, assetID)
-	if c.Debug {
-		log.Println("----")
-		log.Printf("GenerateSignedProxyUrl: %s %v", proxyEndpoint, header)
-	}
-	resp, err := c.get(proxyEndpoint, nil, header)
+	c.log().Debug("GenerateSignedProxyUrl", F("endpoint", proxyEndpoint))
+	response, err := doJSON[GetResponse](ctx, c, apiCall{method: http.MethodGet, path: proxyEndpoint, header: header})
 	if err != nil {
-		if c.Debug {
-			log.Printf("IClient.get(%s) returned an error: %v\n", proxyEndpoint, err)
-		}
 		return "", err
 	}
-
-	return c.parseUrlResponse(resp)
+	return uniqueURL(response.Objects)
 }
 
 // the documentation says that you can just add the query parameter "generate_signed_url=true" to the fileEndpointTemplate
 // but when you do that, you get a download URL which, when going to fetch it, doesn't have correct content-disposition nor filename
 // so you have to do this roundabout way instead: first get the fileID, then call fileEndpointTemplate2 which gives you the download URL
 func (c *IClient) GenerateSignedFileUrl(assetID string) (string, error) {
+	return c.GenerateSignedFileUrlContext(context.Background(), assetID)
+}
+
+// GenerateSignedFileUrlContext is like GenerateSignedFileUrl but uses ctx for every request it makes.
+func (c *IClient) GenerateSignedFileUrlContext(ctx context.Context, assetID string) (string, error) {
 	header := make(http.Header)
 	header.Add("asset_id", assetID)
 	fileEndpoint := fmt.Sprintf(fileEndpointTemplate, assetID)
-	if c.Debug {
-		log.Println("----")
-		log.Printf("GenerateSignedFileUrl: %s %v", fileEndpoint, header)
-	}
-	resp, err := c.get(fileEndpoint, nil, header)
-	if err != nil {
-		if c.Debug {
-			log.Printf("IClient.get(%s) returned an error: %v\n", fileEndpoint, err)
-		}
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
+	c.log().Debug("GenerateSignedFileUrl", F("endpoint", fileEndpoint))
 	type Response struct {
 		Objects []struct {
 			ID string `json:"id"` // this is the fileID
 		}
 	}
-	var r Response
-	if err := json.Unmarshal(body, &r); err != nil {
-		log.Fatalf("Error decoding JSON: %v", err)
-	}
-
-	// now that we have the fileID, go and get the signed URL
-	fileEndpoint2 := fmt.Sprintf(fileEndpointTemplate2, assetID, r.Objects[0].ID)
-	if c.Debug {
-		log.Println("----")
-		log.Printf("GenerateSignedFileUrl: %s %v", fileEndpoint, header)
-	}
-	resp, err = c.get(fileEndpoint2, nil, header)
+	r, err := doJSON[Response](ctx, c, apiCall{method: http.MethodGet, path: fileEndpoint, header: header})
 	if err != nil {
-		if c.Debug {
-			log.Printf("IClient.get(%s) returned an error: %v\n", fileEndpoint, err)
-		}
 		return "", err
 	}
-	return c.parseObjectResponse(resp)
-}
-
-func (c *IClient) postAndGetID(endpoint string, reqBody io.Reader, header http.Header) (string, error) {
-	resp, err := c.post(endpoint, reqBody, header)
-	if err != nil {
-		if c.Debug {
-			log.Printf("IClient.post(%s, %v) returned an error: %v\n", endpoint, reqBody, err)
-		}
-		return "", err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	if c.Debug {
-		log.Printf("Response: %s", body)
-	}
-	if resp.StatusCode != 201 {
-		iErr := &IError{}
-		if err := json.Unmarshal(body, iErr); err != nil || len(iErr.Errors) == 0 {
-			return "", &IError{
-				Errors: []string{fmt.Sprintf("HTTP %d: %s", resp.StatusCode, string(body))},
-			}
-		}
-		return "", iErr
-	}
-
-	type IDResponse struct {
-		Id string `json:"id"`
-	}
-	idResponse := IDResponse{}
-	err = json.Unmarshal(body, &idResponse)
-	if err != nil {
-		return "", err
-	}
-	return idResponse.Id, nil
-}
-
-func (c *IClient) patchAndGetID(endpoint string, reqBody io.Reader, header http.Header) (string, error) {
-	req, err := c.newRequest(http.MethodPatch, endpoint, reqBody, header)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		if c.Debug {
-			log.Printf("IClient.post(%s, %v) returned an error: %v\n", endpoint, reqBody, err)
-		}
-		return "", err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	if c.Debug {
-		log.Printf("Response: %s", body)
-	}
-	if resp.StatusCode != 201 {
-		return "", iErrorFromBody(resp.StatusCode, body)
+	if len(r.Objects) == 0 {
+		return "", fmt.Errorf("%w: asset %s has no files", ErrNotFound, assetID)
 	}
 
-	type IDResponse struct {
-		Id string `json:"id"`
-	}
-	idResponse := IDResponse{}
-	err = json.Unmarshal(body, &idResponse)
+	// now that we have the fileID, go and get the signed URL
+	fileEndpoint2 := fmt.Sprintf(fileEndpointTemplate2, assetID, r.Objects[0].ID)
+	c.log().Debug("GenerateSignedFileUrl", F("endpoint", fileEndpoint2))
+	response, err := doJSON[Object](ctx, c, apiCall{method: http.MethodGet, path: fileEndpoint2, header: header})
 	if err != nil {
 		return "", err
 	}
-	return idResponse.Id, nil
+	return response.URL, nil
 }
 
-func (c *IClient) getAndGetID(endpoint string, reqBody io.Reader, header http.Header) (string, error) {
-	resp, err := c.get(endpoint, reqBody, header)
-	if err != nil {
-		if c.Debug {
-			log.Printf("IClient.get(%s) returned an error: %v\n", endpoint, err)
-		}
-		return "", err
-	}
-	defer resp.Body.Close()
-	type IDResponse struct {
-		Id string `json:"id"`
-	}
-	idResponse := IDResponse{}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	if resp.StatusCode != 200 {
-		return "", iErrorFromBody(resp.StatusCode, body)
-	}
-	err = json.Unmarshal(body, &idResponse)
-	if err != nil {
-		return "", err
-	}
-	return idResponse.Id, nil
+// GetCollectionID will return the ID of the collection with the given name.
+func (c *IClient) GetCollectionIDs(collectionName string) ([]*CollectionResult, error) {
+	return c.GetCollectionIDsContext(context.Background(), collectionName)
 }
 
-// GetCollectionID will return the ID of the collection with the given name.
-func (c IClient) GetCollectionIDs(collectionName string) ([]*CollectionResult, error) {
-	collectionResp, err := c.SearchWithTitleAndTag(collectionName, "", true)
+// GetCollectionIDsContext is like GetCollectionIDs but uses ctx for every request it makes.
+func (c *IClient) GetCollectionIDsContext(ctx context.Context, collectionName string) ([]*CollectionResult, error) {
+	collectionResp, err := c.SearchWithTitleAndTagContext(ctx, collectionName, "", true)
 	if err != nil {
 		return nil, err
 	}
-	var getColPath func(string) (string, error)
-	getColPath = func(collectionID string) (string, error) {
-		collectionEndpoint := fmt.Sprintf(collectionEndpointTemplate, collectionID)
-		resp, err := c.get(collectionEndpoint, nil, http.Header{})
-		if err != nil {
-			return "", err
-		}
-		response := IconikObject{}
-		defer resp.Body.Close()
-
-		// Read response body
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return "", err
+	cache := c.collectionCache()
+	fetch := func(collectionID string) func(context.Context) (*IconikObject, error) {
+		return func(ctx context.Context) (*IconikObject, error) {
+			collectionEndpoint := fmt.Sprintf(collectionEndpointTemplate, collectionID)
+			return doJSON[IconikObject](ctx, c, apiCall{method: http.MethodGet, path: collectionEndpoint})
 		}
-
-		if c.Debug {
-			log.Printf("Response: %s", body)
+	}
+	// getColPath returns the path of a collection, or "" if it is missing
+	// or deleted. seen holds the collections below
+	// it on the current chain, to detect cycles.
+	var getColPath func(string, map[string]bool) (string, error)
+	getColPath = func(collectionID string, seen map[string]bool) (string, error) {
+		if seen[collectionID] {
+			return "", fmt.Errorf("collection %s: %w", collectionID, ErrCollectionCycle)
 		}
-
-		// Check response code
-		switch resp.StatusCode {
-		case 200: // Response is OK
-		case 404:
-			return "", nil
-		default:
-			return "", iErrorFromBody(resp.StatusCode, body)
+		if len(seen) > maxCollectionDepth {
+			return "", fmt.Errorf("collection %s is nested more than %d levels deep", collectionID, maxCollectionDepth)
 		}
-		err = json.Unmarshal(body, &response)
+		response, err := cache.get(ctx, collectionID, fetch(collectionID))
 		if err != nil {
 			return "", err
 		}
-		if response.Status == "DELETED" {
+		if response == nil || response.Status == "DELETED" {
 			return "", nil
 		}
 		if len(response.InCollections) == 0 {
 			return response.Title, nil
 		}
-		next, err := getColPath(response.InCollections[0])
+		seen[collectionID] = true
+		next, err := getColPath(response.InCollections[0], seen)
+		delete(seen, collectionID)
 		if err != nil {
 			return "", err
 		}
 		return fmt.Sprintf("%s/%s", next, response.Title), nil
 	}
 
-	retVal := []*CollectionResult{}
+	// Resolve every (collection, ancestor) pair concurrently; the cache
+	// makes sure shared ancestors are fetched once.
+	type lookup struct {
+		col      IconikObject
+		ancestor string
+		path     string
+	}
+	var lookups []*lookup
 	for _, col := range collectionResp.Objects {
 		for _, ancestor := range col.InCollections {
-			cp, err := getColPath(ancestor)
+			lookups = append(lookups, &lookup{col: col, ancestor: ancestor})
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, collectionLookupConcurrency)
+	for _, l := range lookups {
+		wg.Add(1)
+		go func(l *lookup) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			seen := map[string]bool{l.col.Id: true}
+			cp, err := getColPath(l.ancestor, seen)
 			if err != nil {
-				return nil, err
-			}
-			if len(cp) == 0 {
-				continue
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
 			}
-			retVal = append(retVal, &CollectionResult{
-				Path:         fmt.Sprintf("%s/%s", cp, col.Title),
-				CollectionID: col.Id,
-			})
+			l.path = cp
+		}(l)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	retVal := []*CollectionResult{}
+	for _, l := range lookups {
+		if len(l.path) == 0 {
+			continue
 		}
+		retVal = append(retVal, &CollectionResult{
+			Path:         fmt.Sprintf("%s/%s", l.path, l.col.Title),
+			CollectionID: l.col.Id,
+		})
 	}
 
 	return retVal, nil
 }
 
 // PostAssetID will create an asset with the given title in the given collection.
-func (c IClient) PostAssetID(collectionID, title string) (*PostAssetResponse, error) {
-	endpoint := fmt.Sprintf(postAssetEndpointTemplate)
+func (c *IClient) PostAssetID(collectionID, title string) (*PostAssetResponse, error) {
+	return c.PostAssetIDContext(context.Background(), collectionID, title)
+}
+
+// PostAssetIDContext is like PostAssetID but uses ctx for every request it makes.
+func (c *IClient) PostAssetIDContext(ctx context.Context, collectionID, title string) (*PostAssetResponse, error) {
 	reqBody := map[string]string{
 		"collection_id": collectionID,
 		"title":         title,
 	}
-	if c.Debug {
-		log.Printf("PostAssetID: %s %v", endpoint, reqBody)
-	}
-	reqBodyJSON, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, err
-	}
-	header := make(http.Header)
-	header.Add("accept", "application/json")
-	header.Add("Content-Type", "application/json")
-	resp, err := c.post(endpoint, bytes.NewReader(reqBodyJSON), header)
-	if err != nil {
-		if c.Debug {
-			log.Printf("IClient.post(%s, %v) returned an error: %v\n", endpoint, reqBodyJSON, err)
-		}
-		return nil, err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	if c.Debug {
-		log.Printf("Response (%d): %s", resp.StatusCode, body)
-	}
-
-	if resp.StatusCode != 201 {
-		return nil, iErrorFromBody(resp.StatusCode, body)
-	}
-
-	postAssetResponse := PostAssetResponse{}
-	err = json.Unmarshal(body, &postAssetResponse)
-	if err != nil {
-		return nil, err
-	}
-
-	return &postAssetResponse, nil
+	c.log().Debug("PostAssetID", F("endpoint", postAssetEndpointTemplate), F("body", reqBody))
+	return doJSON[PostAssetResponse](ctx, c, apiCall{method: http.MethodPost, path: postAssetEndpointTemplate, body: reqBody})
 }
 
 // MakeStorageID will create a storage ID for the asset.
 func (c *IClient) MakeStorageID() (string, error) {
-	// now make the storageID
-	storageID, err := c.getAndGetID(storagesMatchingEndpoint, nil, http.Header{})
-	if err != nil {
-		return "", err
-	}
-	return storageID, nil
+	return c.MakeStorageIDContext(context.Background())
+}
+
+// MakeStorageIDContext is like MakeStorageID but uses ctx for every request it makes.
+func (c *IClient) MakeStorageIDContext(ctx context.Context) (string, error) {
+	return c.doID(ctx, apiCall{method: http.MethodGet, path: storagesMatchingEndpoint})
 }
 
 // MakeFormatID will create a format ID for the asset.
 func (c *IClient) MakeFormatID(userID, assetID, mimeType string) (string, error) {
+	return c.MakeFormatIDContext(context.Background(), userID, assetID, mimeType)
+}
+
+// MakeFormatIDContext is like MakeFormatID but uses ctx for every request it makes.
+func (c *IClient) MakeFormatIDContext(ctx context.Context, userID, assetID, mimeType string) (string, error) {
 	// now make the formatID
 	endpoint := fmt.Sprintf(formatIDEndpointTemplate, assetID)
 	type IMD struct {
@@ -702,24 +440,18 @@ func (c *IClient) MakeFormatID(userID, assetID, mimeType string) (string, error)
 	formatIDReqBody := FormatIDReq{
 		UserId:   userID,
 		Name:     "ORIGINAL",
-		Metadata: []IMD{IMD{mimeType}},
-	}
-	reqBodyJSON, err := json.Marshal(formatIDReqBody)
-	if err != nil {
-		return "", err
-	}
-	header := make(http.Header)
-	header.Add("accept", "application/json")
-	header.Add("Content-Type", "application/json")
-	formatID, err := c.postAndGetID(endpoint, bytes.NewReader(reqBodyJSON), header)
-	if err != nil {
-		return "", err
+		Metadata: []IMD{{mimeType}},
 	}
-	return formatID, nil
+	return c.doID(ctx, apiCall{method: http.MethodPost, path: endpoint, body: formatIDReqBody})
 }
 
 // MakeFileSetID will create a fileset ID for the asset.
 func (c *IClient) MakeFileSetID(assetID, formatID, storageID, title, baseDir string) (string, error) {
+	return c.MakeFileSetIDContext(context.Background(), assetID, formatID, storageID, title, baseDir)
+}
+
+// MakeFileSetIDContext is like MakeFileSetID but uses ctx for every request it makes.
+func (c *IClient) MakeFileSetIDContext(ctx context.Context, assetID, formatID, storageID, title, baseDir string) (string, error) {
 	endpoint := fmt.Sprintf(filesetsEndpointTemplate, assetID)
 	type FileSetIDReq struct {
 		FormatID     string   `json:"format_id"`
@@ -735,19 +467,16 @@ func (c *IClient) MakeFileSetID(assetID, formatID, storageID, title, baseDir str
 		Name:         title,
 		ComponentIDs: []string{},
 	}
-	reqBodyJSON, err := json.Marshal(fileSetReqBody)
-	if err != nil {
-		return "", err
-	}
-	fileSetID, err := c.postAndGetID(endpoint, bytes.NewReader(reqBodyJSON), http.Header{})
-	if err != nil {
-		return "", err
-	}
-	return fileSetID, nil
+	return c.doID(ctx, apiCall{method: http.MethodPost, path: endpoint, body: fileSetReqBody})
 }
 
 // GetUploadUrl will get the upload URL for the asset. (only works for BackBlaze right now)
 func (c *IClient) GetUploadUrl(assetID, title, directoryPath, formatID, fileSetID, storageID, fileDateCreated string, fileSize int64) (*FileReqResponse, error) {
+	return c.GetUploadUrlContext(context.Background(), assetID, title, directoryPath, formatID, fileSetID, storageID, fileDateCreated, fileSize)
+}
+
+// GetUploadUrlContext is like GetUploadUrl but uses ctx for every request it makes.
+func (c *IClient) GetUploadUrlContext(ctx context.Context, assetID, title, directoryPath, formatID, fileSetID, storageID, fileDateCreated string, fileSize int64) (*FileReqResponse, error) {
 	endpoint := fmt.Sprintf(uploadUrlEndpointTemplate, assetID)
 	type FileReq struct {
 		OriginalName     string `json:"original_name"`
@@ -773,41 +502,15 @@ func (c *IClient) GetUploadUrl(assetID, title, directoryPath, formatID, fileSetI
 		FileDateCreated:  fileDateCreated,
 		FileDateModified: fileDateCreated,
 	}
-	reqBodyJSON, err := json.Marshal(fileReqBody)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := c.post(endpoint, bytes.NewReader(reqBodyJSON), http.Header{})
-	if err != nil {
-		if c.Debug {
-			log.Printf("IClient.post(%s) returned an error: %v\n", endpoint, err)
-		}
-		return nil, err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	if c.Debug {
-		log.Printf("Response: %s", body)
-	}
-	if resp.StatusCode != 201 {
-		return nil, iErrorFromBody(resp.StatusCode, body)
-	}
-
-	frResponse := FileReqResponse{}
-	err = json.Unmarshal(body, &frResponse)
-	if err != nil {
-		return nil, err
-	}
-	if c.Debug {
-		log.Printf("Upload URL response: %s", string(body))
-	}
-	return &frResponse, nil
+	return doJSON[FileReqResponse](ctx, c, apiCall{method: http.MethodPost, path: endpoint, body: fileReqBody})
 }
 
 func (c *IClient) GetMultipartStartUrl(NAU *NewAssetUpload) error {
+	return c.GetMultipartStartUrlContext(context.Background(), NAU)
+}
+
+// GetMultipartStartUrlContext is like GetMultipartStartUrl but uses ctx for every request it makes.
+func (c *IClient) GetMultipartStartUrlContext(ctx context.Context, NAU *NewAssetUpload) error {
 	endpoint := fmt.Sprintf(multipartStartEndpointTemplate, NAU.AssetID, NAU.FileReqID)
 	type MultipartStartReq struct {
 		AssetID string `json:"asset_id"`
@@ -817,45 +520,15 @@ func (c *IClient) GetMultipartStartUrl(NAU *NewAssetUpload) error {
 		AssetID: NAU.AssetID,
 		FileID:  NAU.FileReqID,
 	}
-	reqBodyJSON, err := json.Marshal(multiStartReqBody)
-	if err != nil {
-		return err
-	}
-	resp, err := c.post(endpoint, bytes.NewReader(reqBodyJSON), http.Header{})
-	if err != nil {
-		if c.Debug {
-			log.Printf("IClient.post(%s) returned an error: %v\n", endpoint, err)
-		}
-		return err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	if c.Debug {
-		log.Printf("Response: %s", body)
-	}
-
-	if resp.StatusCode != 200 {
-		return iErrorFromBody(resp.StatusCode, body)
-	}
-
 	type MultipartStartResp struct {
 		AuthorizationToken string `json:"authorization_token"`
 		UploadFileID       string `json:"upload_file_id"`
 		UploadURL          string `json:"upload_url"`
 	}
-
-	frResponse := MultipartStartResp{}
-	err = json.Unmarshal(body, &frResponse)
+	frResponse, err := doJSON[MultipartStartResp](ctx, c, apiCall{method: http.MethodPost, path: endpoint, body: multiStartReqBody})
 	if err != nil {
 		return err
 	}
-	if c.Debug {
-		log.Printf("Upload URL response: %s", string(body))
-	}
 	NAU.UploadAuthToken = frResponse.AuthorizationToken
 	NAU.UploadURL = frResponse.UploadURL
 	NAU.MultipartFileID = frResponse.UploadFileID
@@ -864,6 +537,11 @@ func (c *IClient) GetMultipartStartUrl(NAU *NewAssetUpload) error {
 
 // PostStartOfJob will post the start of a job.
 func (c *IClient) PostStartOfJob(assetID, title string) (string, error) {
+	return c.PostStartOfJobContext(context.Background(), assetID, title)
+}
+
+// PostStartOfJobContext is like PostStartOfJob but uses ctx for every request it makes.
+func (c *IClient) PostStartOfJobContext(ctx context.Context, assetID, title string) (string, error) {
 	type JobReq struct {
 		ObjectType string `json:"object_type"`
 		ObjectID   string `json:"object_id"`
@@ -878,57 +556,69 @@ func (c *IClient) PostStartOfJob(assetID, title string) (string, error) {
 		Status:     "STARTED",
 		Title:      title,
 	}
-	reqBodyJSON, err := json.Marshal(jobReqBody)
+	jobID, err := c.doID(ctx, apiCall{method: http.MethodPost, path: jobStartEndpointTemplate, body: jobReqBody})
 	if err != nil {
 		return "", err
 	}
-	jobID, err := c.postAndGetID(jobStartEndpointTemplate, bytes.NewReader(reqBodyJSON), http.Header{})
-	if err != nil {
-		return "", err
-	}
-	if c.Debug {
-		log.Printf("jobID: %s", jobID)
-	}
+	c.log().Debug("PostStartOfJob", F("job_id", jobID))
 	return jobID, nil
 }
 
 // MakeNewAsset will create a new asset with the given title in the given collection.
 // This ties together many steps needed to actually upload a file. Ultimately, it returns
 // a NewAssetUpload object that contains all the information needed to upload a file. Once
-// done, you can call FinishUpload to finish the upload.
+// done, you can call FinishUpload to finish the upload. If a step fails after the asset
+// has been created, the asset is deleted again.
 func (c *IClient) MakeNewAsset(collectionID, fileName, title, storagePath, mimeType string, fileSize int64, fileDateCreated time.Time) (*NewAssetUpload, error) {
+	return c.MakeNewAssetContext(context.Background(), collectionID, fileName, title, storagePath, mimeType, fileSize, fileDateCreated)
+}
+
+// MakeNewAssetContext is like MakeNewAsset but uses ctx for every request it makes.
+func (c *IClient) MakeNewAssetContext(ctx context.Context, collectionID, fileName, title, storagePath, mimeType string, fileSize int64, fileDateCreated time.Time) (*NewAssetUpload, error) {
+	return c.makeNewAsset(ctx, collectionID, title, storagePath, mimeType, fileSize, fileDateCreated, fileSize > MULTIPART_FILESIZE_THRESHOLD)
+}
+
+// makeNewAsset implements MakeNewAssetContext, starting a multipart upload
+// if multipart is set. If a step fails after the asset has been created,
+// the asset is deleted again.
+func (c *IClient) makeNewAsset(ctx context.Context, collectionID, title, storagePath, mimeType string, fileSize int64, fileDateCreated time.Time, multipart bool) (_ *NewAssetUpload, err error) {
 	NAU := &NewAssetUpload{
 		MimeType: mimeType,
 		FileSize: fileSize,
 	}
+	defer func() {
+		if err != nil && NAU.AssetID != "" {
+			c.discardUpload(ctx, NAU)
+		}
+	}()
 
 	// create the Asset
-	postAssetResponse, err := c.PostAssetID(collectionID, title)
+	postAssetResponse, err := c.PostAssetIDContext(ctx, collectionID, title)
 	if err != nil {
 		return nil, err
 	}
 	NAU.AssetID = postAssetResponse.Id
 
 	// now make the storageID
-	storageID, err := c.MakeStorageID()
+	storageID, err := c.MakeStorageIDContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	// now make the formatID
-	formatID, err := c.MakeFormatID(postAssetResponse.CreatedByUser, postAssetResponse.Id, mimeType)
+	formatID, err := c.MakeFormatIDContext(ctx, postAssetResponse.CreatedByUser, postAssetResponse.Id, mimeType)
 	if err != nil {
 		return nil, err
 	}
 
 	// now the filesetID
-	fileSetId, err := c.MakeFileSetID(postAssetResponse.Id, formatID, storageID, title, storagePath)
+	fileSetId, err := c.MakeFileSetIDContext(ctx, postAssetResponse.Id, formatID, storageID, title, storagePath)
 	if err != nil {
 		return nil, err
 	}
 
 	// get upload URL
-	frResponse, err := c.GetUploadUrl(postAssetResponse.Id, title, storagePath, formatID, fileSetId, storageID, fileDateCreated.Format(time.RFC3339), fileSize)
+	frResponse, err := c.GetUploadUrlContext(ctx, postAssetResponse.Id, title, storagePath, formatID, fileSetId, storageID, fileDateCreated.Format(time.RFC3339), fileSize)
 	if err != nil {
 		return nil, err
 	}
@@ -937,14 +627,14 @@ func (c *IClient) MakeNewAsset(collectionID, fileName, title, storagePath, mimeT
 	NAU.UploadFilename = frResponse.UploadFilename
 	NAU.FileReqID = frResponse.Id
 
-	if fileSize > MULTIPART_FILESIZE_THRESHOLD {
-		if err := c.GetMultipartStartUrl(NAU); err != nil {
+	if multipart {
+		if err := c.GetMultipartStartUrlContext(ctx, NAU); err != nil {
 			return nil, err
 		}
 	}
 
 	// Note start of job
-	jobID, err := c.PostStartOfJob(postAssetResponse.Id, title)
+	jobID, err := c.PostStartOfJobContext(ctx, postAssetResponse.Id, title)
 	if err != nil {
 		return nil, err
 	}
@@ -956,6 +646,11 @@ func (c *IClient) MakeNewAsset(collectionID, fileName, title, storagePath, mimeT
 
 // CloseFileRequest will close the file request.
 func (c *IClient) CloseFileRequest(assetID, fileReqID string) error {
+	return c.CloseFileRequestContext(context.Background(), assetID, fileReqID)
+}
+
+// CloseFileRequestContext is like CloseFileRequest but uses ctx for every request it makes.
+func (c *IClient) CloseFileRequestContext(ctx context.Context, assetID, fileReqID string) error {
 	endpoint := fmt.Sprintf(uploadUrlFinishedEndpointTemplate, assetID, fileReqID)
 	type FinishedReq struct {
 		Status            string `json:"status"`
@@ -965,48 +660,29 @@ func (c *IClient) CloseFileRequest(assetID, fileReqID string) error {
 		Status:            "CLOSED",
 		ProgressProcessed: 100,
 	}
-	reqBodyJSON, err := json.Marshal(finishedReqBody)
-	if err != nil {
-		return err
-	}
-	header := make(http.Header)
-	header.Add("accept", "application/json")
-	header.Add("Content-Type", "application/json")
-	resp, err := c.patch(endpoint, bytes.NewReader(reqBodyJSON), header)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != 200 {
-		return iErrorFromBody(resp.StatusCode, body)
-	}
-	return nil
+	_, err := c.send(ctx, apiCall{method: http.MethodPatch, path: endpoint, body: finishedReqBody})
+	return err
 }
 
 // GenerateKeyframes will generate keyframes for the asset.
 func (c *IClient) GenerateKeyframes(assetID, fileReqID string) error {
+	return c.GenerateKeyframesContext(context.Background(), assetID, fileReqID)
+}
+
+// GenerateKeyframesContext is like GenerateKeyframes but uses ctx for every request it makes.
+func (c *IClient) GenerateKeyframesContext(ctx context.Context, assetID, fileReqID string) error {
 	endpoint := fmt.Sprintf(keyframeGenerateEndpointTemplate, assetID, fileReqID)
-	resp, err := c.post(endpoint, nil, http.Header{})
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != 200 {
-		return iErrorFromBody(resp.StatusCode, body)
-	}
-	return nil
+	_, err := c.send(ctx, apiCall{method: http.MethodPost, path: endpoint})
+	return err
 }
 
 // FinishJob will finish the job.
 func (c *IClient) FinishJob(jobID string) error {
+	return c.FinishJobContext(context.Background(), jobID)
+}
+
+// FinishJobContext is like FinishJob but uses ctx for every request it makes.
+func (c *IClient) FinishJobContext(ctx context.Context, jobID string) error {
 	endpoint := fmt.Sprintf(patchJobCompleteEndpointTemplate, jobID)
 	type FinishedJobReq struct {
 		Status            string `json:"status"`
@@ -1016,26 +692,16 @@ func (c *IClient) FinishJob(jobID string) error {
 		Status:            "FINISHED",
 		ProgressProcessed: 100,
 	}
-	reqBodyJSON, err := json.Marshal(finishedJobReqBody)
-	if err != nil {
-		return err
-	}
-	resp, err := c.patch(endpoint, bytes.NewReader(reqBodyJSON), http.Header{})
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	if c.Debug {
-		log.Printf("Response: %s", body)
-	}
-	if resp.StatusCode != 200 {
-		return iErrorFromBody(resp.StatusCode, body)
-	}
+	_, err := c.send(ctx, apiCall{method: http.MethodPatch, path: endpoint, body: finishedJobReqBody})
 	return err
 }
 
 func (c *IClient) FinishMultipartUpload(newAssetUpload *NewAssetUpload) error {
+	return c.FinishMultipartUploadContext(context.Background(), newAssetUpload)
+}
+
+// FinishMultipartUploadContext is like FinishMultipartUpload but uses ctx for every request it makes.
+func (c *IClient) FinishMultipartUploadContext(ctx context.Context, newAssetUpload *NewAssetUpload) error {
 	if len(newAssetUpload.Sha1List) == 0 {
 		return fmt.Errorf("no sha1 list provided")
 	}
@@ -1049,73 +715,60 @@ func (c *IClient) FinishMultipartUpload(newAssetUpload *NewAssetUpload) error {
 		Sha1List:     newAssetUpload.Sha1List,
 		UploadFileID: newAssetUpload.MultipartFileID,
 	}
-	reqBodyJSON, err := json.Marshal(finishMultipartReqBody)
-	if err != nil {
-		return err
-	}
-
-	resp, err := c.post(endpoint, bytes.NewReader(reqBodyJSON), http.Header{})
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	if c.Debug {
-		log.Printf("Response: %s", body)
-	}
-
-	if resp.StatusCode != 200 {
-		return iErrorFromBody(resp.StatusCode, body)
-	}
-	return nil
+	_, err := c.send(ctx, apiCall{method: http.MethodPost, path: endpoint, body: finishMultipartReqBody})
+	return err
 }
 
 // FinishUpload will finish the upload. (call it after uploading the file), uses previously
 // defined steps.
 func (c *IClient) FinishUpload(newAssetUpload *NewAssetUpload) error {
+	return c.FinishUploadContext(context.Background(), newAssetUpload)
+}
+
+// FinishUploadContext is like FinishUpload but uses ctx for every request it makes.
+func (c *IClient) FinishUploadContext(ctx context.Context, newAssetUpload *NewAssetUpload) error {
 	if newAssetUpload.MultipartFileID != "" {
-		if err := c.FinishMultipartUpload(newAssetUpload); err != nil {
+		if err := c.FinishMultipartUploadContext(ctx, newAssetUpload); err != nil {
 			return err
 		}
 	}
 
 	// patch files
-	if err := c.CloseFileRequest(newAssetUpload.AssetID, newAssetUpload.FileReqID); err != nil {
+	if err := c.CloseFileRequestContext(ctx, newAssetUpload.AssetID, newAssetUpload.FileReqID); err != nil {
 		return err
 	}
 
 	// generate keyframes
-	if err := c.GenerateKeyframes(newAssetUpload.AssetID, newAssetUpload.FileReqID); err != nil {
+	if err := c.GenerateKeyframesContext(ctx, newAssetUpload.AssetID, newAssetUpload.FileReqID); err != nil {
 		return err
 	}
 
 	// patch job
-	if err := c.FinishJob(newAssetUpload.JobID); err != nil {
+	if err := c.FinishJobContext(ctx, newAssetUpload.JobID); err != nil {
 		return err
 	}
 	return nil
 }
 
 // CreateCollection creates a new collection with the given title inside the specified
-// parent collection. Returns the UUID of the newly created collection.
+// parent collection, or at the top level if parentCollectionID is empty. Returns the
+// UUID of the newly created collection.
 func (c *IClient) CreateCollection(title, parentCollectionID string) (string, error) {
+	return c.CreateCollectionContext(context.Background(), title, parentCollectionID)
+}
+
+// CreateCollectionContext is like CreateCollection but uses ctx for every request it makes.
+func (c *IClient) CreateCollectionContext(ctx context.Context, title, parentCollectionID string) (string, error) {
 	type createCollectionReq struct {
 		Title    string `json:"title"`
-		ParentID string `json:"parent_id"`
+		ParentID string `json:"parent_id,omitempty"`
 	}
 	reqBody := createCollectionReq{
 		Title:    title,
 		ParentID: parentCollectionID,
 	}
-	reqBodyJSON, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", err
-	}
-	if c.Debug {
-		log.Printf("CreateCollection: %s %s", createCollectionEndpoint, reqBodyJSON)
-	}
-	id, err := c.postAndGetID(createCollectionEndpoint, bytes.NewReader(reqBodyJSON), http.Header{})
+	c.log().Debug("CreateCollection", F("endpoint", createCollectionEndpoint), F("title", title), F("parent_id", parentCollectionID))
+	id, err := c.doID(ctx, apiCall{method: http.MethodPost, path: createCollectionEndpoint, body: reqBody})
 	if err != nil {
 		return "", fmt.Errorf("creating collection %q: %w", title, err)
 	}
@@ -1126,19 +779,12 @@ func (c *IClient) CreateCollection(title, parentCollectionID string) (string, er
 // record associated with the given asset. A file is only marked CLOSED after a
 // successful call to FinishUpload, so a partial or failed upload will return 0.
 func (c *IClient) GetAssetFileSize(assetID string) (int64, error) {
+	return c.GetAssetFileSizeContext(context.Background(), assetID)
+}
+
+// GetAssetFileSizeContext is like GetAssetFileSize but uses ctx for every request it makes.
+func (c *IClient) GetAssetFileSizeContext(ctx context.Context, assetID string) (int64, error) {
 	endpoint := fmt.Sprintf(fileEndpointTemplate, assetID)
-	resp, err := c.get(endpoint, nil, http.Header{})
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return 0, err
-	}
-	if resp.StatusCode != 200 {
-		return 0, iErrorFromBody(resp.StatusCode, body)
-	}
 	type fileObj struct {
 		Size   int64  `json:"size"`
 		Status string `json:"status"`
@@ -1146,8 +792,8 @@ func (c *IClient) GetAssetFileSize(assetID string) (int64, error) {
 	type filesResponse struct {
 		Objects []fileObj `json:"objects"`
 	}
-	var r filesResponse
-	if err := json.Unmarshal(body, &r); err != nil {
+	r, err := doJSON[filesResponse](ctx, c, apiCall{method: http.MethodGet, path: endpoint})
+	if err != nil {
 		return 0, err
 	}
 	for _, f := range r.Objects {
@@ -1,12 +1,15 @@
 package iconik
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestSendRequest(t *testing.T) {
@@ -142,3 +145,44 @@ func TestIClient_GenerateKeyframeUrl(t *testing.T) {
 		t.Errorf("GetKeyframeUrl(%s) got %s; wanted %s", assetId, url, expected)
 	}
 }
+
+func TestIClient_SearchWithTitleAndTagContextCanceled(t *testing.T) {
+	unblock := make(chan struct{})
+	//Start a local HTTP server that never answers until the test is done
+	defer close(unblock)
+	_, client := newTestServer(t, func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-unblock:
+		case <-req.Context().Done():
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.SearchWithTitleAndTagContext(ctx, "testTitle", "", false)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SearchWithTitleAndTagContext() got %v; wanted %v", err, context.DeadlineExceeded)
+	}
+}
+
+// testCredentials authenticate the clients of tests.
+var testCredentials = Credentials{AppID: "testAppID", Token: "testToken"}
+
+// newTestClient returns a client of the API at host configured by opts.
+func newTestClient(t *testing.T, host string, opts ...Option) *IClient {
+	t.Helper()
+	client, err := NewIClientWithOptions(testCredentials, host, opts...)
+	if err != nil {
+		t.Fatalf("NewIClientWithOptions() got error %v", err)
+	}
+	return client
+}
+
+// newTestServer starts a server that answers with handler until the test
+// ends, and returns it with a client of it configured by opts.
+func newTestServer(t *testing.T, handler http.HandlerFunc, opts ...Option) (*httptest.Server, *IClient) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server, newTestClient(t, server.URL, opts...)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	iconik "github.com/jzhang919/iconikclient2"
+)
+
+const usage = `collectionTool reorganizes Iconik collections.
+
+Operations (-op):
+  list    print the tree under -Collection, down to -depth levels
+  mkdir   create -Collection and any missing parents
+  rename  rename -Collection to -title
+  move    move -Collection under the -to collection
+  delete  delete -Collection; with -recursive, delete its contents too,
+          keeping assets that are also in other collections
+  add     add the -object of -type to -Collection
+  remove  remove the -object of -type from -Collection
+
+Collections are given as paths of titles, e.g. Teaching/2024/Fall.
+`
+
+func main() {
+	appIDFlag := flag.String("AppID", "", "Enter your App ID: ")
+	tokenFlag := flag.String("Token", "", "Enter your access token: ")
+	debugFlag := flag.Bool("Debug", false, "Debugging")
+	op := flag.String("op", "list", "Operation to perform")
+	collection := flag.String("Collection", "", "Path of the collection to operate on")
+	title := flag.String("title", "", "New title for rename")
+	to := flag.String("to", "", "Path of the new parent collection for move")
+	makeTo := flag.Bool("MakeCollection", false, "Create the -to path for move if it does not exist")
+	recursive := flag.Bool("recursive", false, "Also delete the collection's contents")
+	object := flag.String("object", "", "ID of the asset or collection to add or remove")
+	objectType := flag.String("type", iconik.ObjectTypeAsset, "Type of -object: assets or collections")
+	depth := flag.Int("depth", 0, "Levels to list; 0 lists everything")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *collection == "" {
+		flag.Usage()
+		log.Fatalf("missing required arg: Collection")
+	}
+	creds := iconik.Credentials{
+		AppID: *appIDFlag,
+		Token: *tokenFlag,
+	}
+	client, err := iconik.NewIClient(creds, "", *debugFlag)
+	if err != nil {
+		log.Fatalf("Unable to create client: %v\n", err)
+	}
+	ctx := context.Background()
+
+	if *op == "mkdir" {
+		id, err := client.EnsureCollectionPath(ctx, *collection)
+		if err != nil {
+			log.Fatalf("mkdir failed: %v", err)
+		}
+		fmt.Printf("%s %s\n", id, *collection)
+		return
+	}
+
+	id, err := client.GetCollectionByPath(ctx, *collection)
+	if err != nil {
+		log.Fatalf("error finding collection %s: %v", *collection, err)
+	}
+
+	switch *op {
+	case "list":
+		err = client.WalkCollection(ctx, id, *depth, func(e iconik.CollectionEntry) error {
+			kind := "asset"
+			if e.Object.ObjectType == iconik.ObjectTypeCollection {
+				kind = "collection"
+			}
+			fmt.Printf("%s%s (%s %s)\n", strings.Repeat("  ", e.Depth-1), e.Object.Title, kind, e.Object.Id)
+			return nil
+		})
+	case "rename":
+		if *title == "" {
+			log.Fatalf("rename needs -title")
+		}
+		err = client.RenameCollection(ctx, id, *title)
+	case "move":
+		if *to == "" {
+			log.Fatalf("move needs -to")
+		}
+		var parentID string
+		if *makeTo {
+			parentID, err = client.EnsureCollectionPath(ctx, *to)
+		} else {
+			parentID, err = client.GetCollectionByPath(ctx, *to)
+		}
+		if err != nil {
+			log.Fatalf("error finding collection %s: %v", *to, err)
+		}
+		err = client.MoveCollection(ctx, id, parentID)
+	case "delete":
+		err = client.DeleteCollection(ctx, id, *recursive)
+	case "add", "remove":
+		if *object == "" {
+			log.Fatalf("%s needs -object", *op)
+		}
+		if *op == "add" {
+			err = client.AddToCollection(ctx, id, *object, *objectType)
+		} else {
+			err = client.RemoveFromCollection(ctx, id, *object, *objectType)
+		}
+	default:
+		flag.Usage()
+		log.Fatalf("unknown operation %q", *op)
+	}
+	if err != nil {
+		log.Fatalf("%s failed: %v", *op, err)
+	}
+}
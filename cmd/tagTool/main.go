@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	iconik "github.com/jzhang919/iconikclient2"
+)
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func main() {
+	appIDFlag := flag.String("AppID", "", "Enter your App ID: ")
+	tokenFlag := flag.String("Token", "", "Enter your access token: ")
+	debugFlag := flag.Bool("Debug", false, "Debugging")
+	viewID := flag.String("View", "", "ID of the metadata view holding the tag field")
+	field := flag.String("Field", iconik.TagField, "Metadata field holding the tags")
+	ids := flag.String("ids", "", "Comma-separated asset IDs to change")
+	searchTitle := flag.String("title", "", "Also change assets with this title")
+	searchTag := flag.String("tag", "", "Also change assets with this tag")
+	add := flag.String("add", "", "Comma-separated tags to add")
+	remove := flag.String("remove", "", "Comma-separated tags to remove")
+	replace := flag.String("replace", "", "Comma-separated tags to set, replacing all others")
+	dryRun := flag.Bool("dryrun", false, "Show which assets would change without changing them")
+	flag.Parse()
+
+	creds := iconik.Credentials{
+		AppID: *appIDFlag,
+		Token: *tokenFlag,
+	}
+	client, _ := iconik.NewIClient(creds, "", *debugFlag)
+
+	target := iconik.TagTarget{AssetIDs: splitList(*ids)}
+	if *searchTitle != "" || *searchTag != "" {
+		q := iconik.NewSearchQuery().IncludeFields("id")
+		if *searchTitle != "" {
+			q.Where(iconik.Match("title", *searchTitle))
+		}
+		if *searchTag != "" {
+			q.Where(iconik.Match(iconik.Metadata(*field), *searchTag))
+		}
+		target.Query = q
+	}
+	if len(target.AssetIDs) == 0 && target.Query == nil {
+		log.Fatalf("Nothing to change: pass -ids, -title or -tag")
+	}
+	opts := iconik.TagOptions{ViewID: *viewID, Field: *field, DryRun: *dryRun}
+
+	ctx := context.Background()
+	var changes []iconik.TagChange
+	var err error
+	switch {
+	case *replace != "":
+		changes, err = client.ReplaceTags(ctx, target, splitList(*replace), opts)
+	case *add != "" && *remove != "":
+		log.Fatalf("Pass either -add or -remove, not both")
+	case *add != "":
+		changes, err = client.AddTags(ctx, target, splitList(*add), opts)
+	case *remove != "":
+		changes, err = client.RemoveTags(ctx, target, splitList(*remove), opts)
+	default:
+		log.Fatalf("Pass one of -add, -remove or -replace")
+	}
+
+	verb := "Changed"
+	if *dryRun {
+		verb = "Would change"
+	}
+	for _, change := range changes {
+		fmt.Printf("%s %s: %v -> %v\n", verb, change.AssetID, change.Before, change.After)
+	}
+	fmt.Printf("%s %d assets\n", verb, len(changes))
+	if err != nil {
+		log.Fatalf("Tagging failed: %v\n", err)
+	}
+}
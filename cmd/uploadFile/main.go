@@ -1,20 +1,71 @@
 package main
 
 import (
-	"bytes"
-	"crypto/sha1"
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	iconik "github.com/jzhang919/iconikclient2"
 )
 
+// resolveCollection returns the ID of the collection named by the
+// -Collection flag. A value containing a slash is an exact path from a
+// top-level collection; a bare name must match exactly one collection.
+func resolveCollection(client *iconik.IClient, collection string, create bool) (string, error) {
+	ctx := context.Background()
+	if create {
+		return client.EnsureCollectionPath(ctx, collection)
+	}
+	if strings.Contains(collection, "/") {
+		return client.GetCollectionByPath(ctx, collection)
+	}
+	results, err := client.GetCollectionIDsContext(ctx, collection)
+	if err != nil {
+		return "", err
+	}
+	// Search matches titles loosely and can return a collection more than
+	// once, so keep one result per collection whose title is exactly name.
+	var collectionIDs []*iconik.CollectionResult
+	seen := map[string]bool{}
+	for _, v := range results {
+		if !strings.HasSuffix(v.Path, "/"+collection) || seen[v.CollectionID] {
+			continue
+		}
+		seen[v.CollectionID] = true
+		collectionIDs = append(collectionIDs, v)
+	}
+	switch len(collectionIDs) {
+	case 0:
+		// GetCollectionIDs only reports nested collections.
+		return client.GetCollectionByPath(ctx, collection)
+	case 1:
+		return collectionIDs[0].CollectionID, nil
+	}
+	for _, v := range collectionIDs {
+		log.Printf("candidate collection: %s (%s)", v.Path, v.CollectionID)
+	}
+	return "", fmt.Errorf("%d collections are named %q; pass the full path instead", len(collectionIDs), collection)
+}
+
+// logProgress prints the progress of the upload.
+func logProgress(p iconik.UploadProgress) {
+	percent := float64(0)
+	if p.TotalBytes > 0 {
+		percent = float64(p.BytesSent) * 100 / float64(p.TotalBytes)
+	}
+	part := ""
+	if p.Part != 0 {
+		part = fmt.Sprintf(", part %d/%d done", p.Part, p.Parts)
+	}
+	log.Printf("%.1f%% (%d/%d bytes%s), %.1f MB/s, %s left", percent, p.BytesSent, p.TotalBytes, part, p.Throughput/1e6, p.ETA.Round(time.Second))
+}
+
 // this app will take a local file and upload it to Backblaze B2 and then ingest it into Iconik
 func main() {
 	appID := flag.String("AppID", "", "Enter your App ID: ")
@@ -22,8 +73,14 @@ func main() {
 	debug := flag.Bool("Debug", false, "Debugging")
 	fileName := flag.String("Filename", "", "file that you want to upload (local full path)")
 	title := flag.String("Title", "", "title you want to see in Iconik")
-	collection := flag.String("Collection", "", "collection you want to add the asset to")
+	collection := flag.String("Collection", "", "collection you want to add the asset to, either a name or a path like Teaching/2024/Fall")
+	makeCollection := flag.Bool("MakeCollection", false, "create the -Collection path if it does not exist")
 	storagePath := flag.String("StoragePath", "/", "storage path you want to save to in B2")
+	partSizeMB := flag.Int64("PartSizeMB", 0, "size of each part of a multipart upload in MB, 5 to 5120 (default 100)")
+	concurrency := flag.Int("Concurrency", iconik.DefaultUploadConcurrency, "number of parts to upload at once")
+	checkpoint := flag.String("Checkpoint", "", "file to save upload progress to, so a failed upload can be resumed with -Resume")
+	resume := flag.Bool("Resume", false, "resume the upload saved in -Checkpoint; a stale one is cleaned up and the file uploaded afresh")
+	progressInterval := flag.Duration("ProgressInterval", iconik.DefaultProgressInterval, "how often to report upload progress")
 	flag.Parse()
 
 	if *appID == "" || *token == "" || *fileName == "" || *title == "" || *collection == "" {
@@ -37,19 +94,15 @@ func main() {
 	if err != nil {
 		log.Fatalf("Unable to create client: %v\n", err)
 	}
-
-	collectionIDs, err := client.GetCollectionIDs(*collection)
-	if err != nil {
-		log.Fatalf("error getting collectionID: %v", err)
-	}
-	for i, v := range collectionIDs {
-		if i == 0 {
-			log.Printf("Using collectionID entry: %v", v)
-		} else {
-			log.Printf("(unused) collectionID entry: %v", v)
-		}
+	if *resume && *checkpoint == "" {
+		log.Fatalf("-Resume needs -Checkpoint")
 	}
-	// open file and get its size, creation date and mimeType
+
+	// stop the upload on Ctrl-C; without -Checkpoint the asset is then deleted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// open file and get its size and creation date
 	file, err := os.Open(*fileName)
 	if err != nil {
 		log.Fatalf("error opening file: %v", err)
@@ -59,121 +112,52 @@ func main() {
 	if err != nil {
 		log.Fatalf("error getting file info: %v", err)
 	}
-	fileSize := fileInfo.Size()
-	// get it's date last modified
-	fileDateCreated := fileInfo.ModTime()
-	uploadBody, err := io.ReadAll(file)
-	if err != nil {
-		log.Fatalf("error reading file: %v", err)
+
+	if *resume {
+		assetID, err := client.ResumeUpload(ctx, file, fileInfo.Size(), iconik.UploadOptions{
+			CheckpointPath:   *checkpoint,
+			Concurrency:      *concurrency,
+			Progress:         logProgress,
+			ProgressInterval: *progressInterval,
+		})
+		switch {
+		case err == nil:
+			log.Printf("success! asset ID: %s", assetID)
+			return
+		case errors.Is(err, iconik.ErrStaleCheckpoint):
+			log.Printf("%v; starting over", err)
+		default:
+			log.Fatalf("error resuming upload: %v", err)
+		}
 	}
-	// get mimetype
-	mimeType := http.DetectContentType(uploadBody)
 
-	// create the stubs for the upload
-	NAU, err := client.MakeNewAsset(collectionIDs[0].CollectionID, *fileName, *title, *storagePath, mimeType, fileSize, fileDateCreated)
+	collectionID, err := resolveCollection(client, *collection, *makeCollection)
 	if err != nil {
-		log.Fatalf("error making new asset: %v", err)
+		log.Fatalf("error getting collectionID: %v", err)
 	}
-
-	// check if we are doing single file upload or multipart
-	if NAU.MultipartFileID != "" {
-		partNum := 1
-		shas := []string{}
-
-		for {
-			startPos := int64(partNum-1) * iconik.MULTIPART_FILESIZE_THRESHOLD
-			endPos := startPos + iconik.MULTIPART_FILESIZE_THRESHOLD
-			if endPos > fileSize {
-				endPos = fileSize
-			}
-			if startPos >= fileSize {
-				break
-			}
-
-			uploadReq, err := http.NewRequest(http.MethodPost,
-				NAU.UploadURL,
-				bytes.NewBuffer(uploadBody[startPos:endPos]))
-			if err != nil {
-				log.Fatalf("can't make upload request: %v", err)
-			}
-
-			// Calculate SHA1 of the body slice
-			hasher := sha1.New()
-			hasher.Write(uploadBody[startPos:endPos])
-			sha1Hash := hasher.Sum(nil)
-
-			uploadReq.Header.Set("Authorization", NAU.UploadAuthToken)
-			uploadReq.Header.Set("X-Bz-Part-Number", fmt.Sprintf("%d", partNum))
-			uploadReq.Header.Set("Content-Length", fmt.Sprintf("%d", (endPos-startPos)))
-			uploadReq.Header.Set("X-Bz-Content-Sha1", fmt.Sprintf("%x", sha1Hash))
-
-			// Send the request
-			uploadClient := &http.Client{}
-			resp, err := uploadClient.Do(uploadReq)
-			if err != nil {
-				log.Fatalf("can't upload file: %v", err)
-			}
-			defer resp.Body.Close()
-			respBody, err := io.ReadAll(resp.Body)
-			if err != nil {
-				log.Fatalf("can't read upload response: %v", err)
-			}
-			// Check the response
-			if resp.StatusCode != http.StatusOK {
-				log.Fatalf("Bad status during upload: %s because %s", resp.Status, string(respBody))
-			}
-			type MultiResp struct {
-				ContentSha1 string `json:"contentSha1"`
-			}
-			var multiResp MultiResp
-			err = json.Unmarshal(respBody, &multiResp)
-			if err != nil {
-				log.Fatalf("error unmarshalling multipart response: %v", err)
-			}
-			shas = append(shas, multiResp.ContentSha1)
-			partNum++
-		}
-		NAU.Sha1List = shas
-	} else {
-		// upload file
-		uploadReq, err := http.NewRequest(http.MethodPost,
-			NAU.UploadURL,
-			bytes.NewBuffer(uploadBody))
-		if err != nil {
-			log.Fatalf("can't make upload request: %v", err)
-		}
-
-		// Calculate SHA1 of the body slice
-		hasher := sha1.New()
-		hasher.Write(uploadBody)
-		sha1Hash := hasher.Sum(nil)
-
-		uploadReq.Header.Set("Authorization", NAU.UploadAuthToken)
-		uploadReq.Header.Set("X-Bz-File-Name", url.PathEscape(NAU.UploadFilename))
-		uploadReq.Header.Set("X-Bz-Content-Sha1", fmt.Sprintf("%x", sha1Hash))
-		uploadReq.Header.Add("Content-Type", NAU.MimeType)
-
-		// Send the request
-		uploadClient := &http.Client{}
-		resp, err := uploadClient.Do(uploadReq)
-		if err != nil {
-			log.Fatalf("can't upload file: %v", err)
-		}
-		defer resp.Body.Close()
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Fatalf("can't read upload response: %v", err)
+	log.Printf("Using collectionID: %s", collectionID)
+
+	assetID, err := client.UploadFile(ctx, file, fileInfo.Size(), iconik.UploadOptions{
+		CollectionID:     collectionID,
+		FileName:         *fileName,
+		Title:            *title,
+		StoragePath:      *storagePath,
+		DateCreated:      fileInfo.ModTime(),
+		PartSize:         *partSizeMB * 1024 * 1024,
+		Concurrency:      *concurrency,
+		CheckpointPath:   *checkpoint,
+		Progress:         logProgress,
+		ProgressInterval: *progressInterval,
+	})
+	if err != nil {
+		if assetID != "" {
+			log.Printf("the file is in asset %s, but finishing the upload failed", assetID)
 		}
-		// Check the response
-		if resp.StatusCode != http.StatusOK {
-			log.Fatalf("Bad status during upload: %s because %s", resp.Status, string(respBody))
+		if *checkpoint != "" {
+			log.Printf("progress saved in %s; rerun with -Resume to continue", *checkpoint)
 		}
+		log.Fatalf("error uploading file: %v", err)
 	}
 
-	err = client.FinishUpload(NAU)
-	if err != nil {
-		log.Fatalf("error is %v", err)
-	}
-
-	log.Printf("success!")
+	log.Printf("success! asset ID: %s", assetID)
 }
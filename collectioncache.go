@@ -0,0 +1,199 @@
+package iconik
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Defaults for NewCollectionCache.
+const (
+	DefaultCollectionCacheSize = 10000
+	DefaultCollectionCacheTTL  = 5 * time.Minute
+)
+
+// ErrCollectionCycle is matched by errors.Is when a collection turns out to
+// be its own ancestor.
+var ErrCollectionCycle = errors.New("iconik: collection is its own ancestor")
+
+// CollectionCache remembers collection records fetched while resolving
+// collection paths, so that ancestors shared by many collections are only
+// fetched once. It holds at most size records, evicting the least recently
+// used, and refetches records older than its TTL. Concurrent lookups of the
+// same collection share a single request. It is safe for concurrent use.
+// The zero value is an empty cache with the default size and TTL.
+type CollectionCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu       sync.Mutex
+	lru      *list.List // of *collectionCacheEntry, most recent first
+	entries  map[string]*list.Element
+	inflight map[string]*collectionFetch
+}
+
+type collectionCacheEntry struct {
+	id      string
+	obj     *IconikObject // nil if the collection does not exist
+	expires time.Time
+}
+
+// collectionFetch is a request for a collection that other lookups can wait
+// on.
+type collectionFetch struct {
+	done chan struct{}
+	obj  *IconikObject
+	err  error
+	// cancelled is set if the fetch failed because its caller's context
+	// ended, which says nothing about the collection.
+	cancelled bool
+}
+
+// NewCollectionCache creates a cache of up to size collections that keeps
+// each for ttl. Values below one mean DefaultCollectionCacheSize and
+// DefaultCollectionCacheTTL.
+func NewCollectionCache(size int, ttl time.Duration) *CollectionCache {
+	if size < 1 {
+		size = DefaultCollectionCacheSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultCollectionCacheTTL
+	}
+	return &CollectionCache{size: size, ttl: ttl}
+}
+
+// init fills in whatever a zero CollectionCache lacks. cc.mu must be held.
+func (cc *CollectionCache) init() {
+	if cc.size < 1 {
+		cc.size = DefaultCollectionCacheSize
+	}
+	if cc.ttl <= 0 {
+		cc.ttl = DefaultCollectionCacheTTL
+	}
+	if cc.now == nil {
+		cc.now = time.Now
+	}
+	if cc.lru == nil {
+		cc.lru = list.New()
+		cc.entries = make(map[string]*list.Element)
+		cc.inflight = make(map[string]*collectionFetch)
+	}
+}
+
+// Invalidate drops a collection from the cache.
+func (cc *CollectionCache) Invalidate(collectionID string) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.init()
+	if e, ok := cc.entries[collectionID]; ok {
+		cc.lru.Remove(e)
+		delete(cc.entries, collectionID)
+	}
+}
+
+// Purge drops every collection from the cache.
+func (cc *CollectionCache) Purge() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.init()
+	cc.lru.Init()
+	cc.entries = make(map[string]*list.Element)
+}
+
+// Len returns the number of collections in the cache, including expired
+// ones not yet evicted.
+func (cc *CollectionCache) Len() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.init()
+	return cc.lru.Len()
+}
+
+// get returns the cached record of a collection, calling fetch if it is
+// missing or expired. A nil record with a nil error means the collection
+// does not exist; that answer is cached too. Lookups waiting on a fetch
+// whose caller gave up retry rather than inherit its cancellation.
+func (cc *CollectionCache) get(ctx context.Context, id string, fetch func(context.Context) (*IconikObject, error)) (*IconikObject, error) {
+	for {
+		cc.mu.Lock()
+		cc.init()
+		if e, ok := cc.entries[id]; ok {
+			entry := e.Value.(*collectionCacheEntry)
+			if cc.now().Before(entry.expires) {
+				cc.lru.MoveToFront(e)
+				cc.mu.Unlock()
+				return entry.obj, nil
+			}
+			cc.lru.Remove(e)
+			delete(cc.entries, id)
+		}
+		f, ok := cc.inflight[id]
+		if !ok {
+			break
+		}
+		cc.mu.Unlock()
+		select {
+		case <-f.done:
+			if f.cancelled {
+				continue
+			}
+			return f.obj, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	f := &collectionFetch{done: make(chan struct{})}
+	cc.inflight[id] = f
+	cc.mu.Unlock()
+
+	f.obj, f.err = fetch(ctx)
+	if errors.Is(f.err, ErrNotFound) {
+		f.obj, f.err = nil, nil
+	}
+	f.cancelled = f.err != nil && ctx.Err() != nil
+
+	cc.mu.Lock()
+	delete(cc.inflight, id)
+	if f.err == nil {
+		cc.add(id, f.obj)
+	}
+	cc.mu.Unlock()
+	close(f.done)
+	return f.obj, f.err
+}
+
+// add stores a record, evicting the least recently used ones beyond the
+// size limit. cc.mu must be held.
+func (cc *CollectionCache) add(id string, obj *IconikObject) {
+	cc.init()
+	if e, ok := cc.entries[id]; ok {
+		cc.lru.Remove(e)
+	}
+	entry := &collectionCacheEntry{id: id, obj: obj, expires: cc.now().Add(cc.ttl)}
+	cc.entries[id] = cc.lru.PushFront(entry)
+	for cc.lru.Len() > cc.size {
+		oldest := cc.lru.Back()
+		cc.lru.Remove(oldest)
+		delete(cc.entries, oldest.Value.(*collectionCacheEntry).id)
+	}
+}
+
+// collectionCache returns the client's CollectionCache, or a new one that
+// lives only as long as the caller keeps it if none is configured.
+func (c *IClient) collectionCache() *CollectionCache {
+	if c.CollectionCache != nil {
+		return c.CollectionCache
+	}
+	return NewCollectionCache(0, 0)
+}
+
+// invalidateCollection drops a collection the client has just changed from
+// the configured cache, if any.
+func (c *IClient) invalidateCollection(collectionID string) {
+	if c.CollectionCache != nil {
+		c.CollectionCache.Invalidate(collectionID)
+	}
+}
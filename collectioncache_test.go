@@ -0,0 +1,252 @@
+package iconik
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCollectionCache_EvictionAndTTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	cc := NewCollectionCache(2, time.Minute)
+	cc.now = func() time.Time { return now }
+	fetches := 0
+	fetch := func(id string) func(context.Context) (*IconikObject, error) {
+		return func(context.Context) (*IconikObject, error) {
+			fetches++
+			return &IconikObject{Id: id}, nil
+		}
+	}
+	get := func(id string) {
+		if obj, err := cc.get(context.Background(), id, fetch(id)); err != nil || obj.Id != id {
+			t.Fatalf("get(%s) got %v, %v", id, obj, err)
+		}
+	}
+
+	get("a")
+	get("b")
+	get("a") // cached; makes b the least recently used
+	get("c") // evicts b
+	if fetches != 3 || cc.Len() != 2 {
+		t.Errorf("got %d fetches and %d entries; wanted 3 and 2", fetches, cc.Len())
+	}
+	get("a")
+	get("b")
+	if fetches != 4 {
+		t.Errorf("got %d fetches after evicting b; wanted 4", fetches)
+	}
+
+	now = now.Add(2 * time.Minute)
+	get("b")
+	if fetches != 5 {
+		t.Errorf("got %d fetches after expiry; wanted 5", fetches)
+	}
+
+	cc.Invalidate("b")
+	get("b")
+	if fetches != 6 {
+		t.Errorf("got %d fetches after Invalidate; wanted 6", fetches)
+	}
+}
+
+func TestCollectionCache_ZeroValue(t *testing.T) {
+	cc := &CollectionCache{}
+	if cc.Len() != 0 {
+		t.Errorf("Len() of a zero cache got %d; wanted 0", cc.Len())
+	}
+	fetches := 0
+	fetch := func(context.Context) (*IconikObject, error) {
+		fetches++
+		return &IconikObject{Id: "a"}, nil
+	}
+	for i := 0; i < 2; i++ {
+		if obj, err := cc.get(context.Background(), "a", fetch); err != nil || obj.Id != "a" {
+			t.Fatalf("get(a) got %v, %v", obj, err)
+		}
+	}
+	if fetches != 1 || cc.Len() != 1 {
+		t.Errorf("got %d fetches and %d entries; wanted 1 and 1", fetches, cc.Len())
+	}
+	cc.Invalidate("a")
+	cc.Purge()
+}
+
+func TestCollectionCache_NotFoundAndErrors(t *testing.T) {
+	cc := NewCollectionCache(0, 0)
+	calls := 0
+	notFound := func(context.Context) (*IconikObject, error) {
+		calls++
+		return nil, &APIError{StatusCode: http.StatusNotFound}
+	}
+	for i := 0; i < 2; i++ {
+		if obj, err := cc.get(context.Background(), "gone", notFound); obj != nil || err != nil {
+			t.Errorf("get(gone) got %v, %v; wanted nil, nil", obj, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("not-found collection fetched %d times; wanted 1", calls)
+	}
+
+	failure := errors.New("boom")
+	calls = 0
+	failing := func(context.Context) (*IconikObject, error) {
+		calls++
+		return nil, failure
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := cc.get(context.Background(), "broken", failing); err != failure {
+			t.Errorf("get(broken) got %v; wanted %v", err, failure)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("failing collection fetched %d times; wanted 2, errors must not be cached", calls)
+	}
+}
+
+func TestCollectionCache_SharesInflightFetches(t *testing.T) {
+	cc := NewCollectionCache(0, 0)
+	var calls int32
+	release := make(chan struct{})
+	fetch := func(context.Context) (*IconikObject, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &IconikObject{Id: "a"}, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cc.get(context.Background(), "a", fetch)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("concurrent lookups fetched %d times; wanted 1", calls)
+	}
+}
+
+func TestCollectionCache_WaiterOutlivesCancelledFetch(t *testing.T) {
+	cc := NewCollectionCache(0, 0)
+	started := make(chan struct{}, 2)
+	fetch := func(ctx context.Context) (*IconikObject, error) {
+		started <- struct{}{}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+			return &IconikObject{Id: "a"}, nil
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error)
+	go func() {
+		_, err := cc.get(ctx, "a", fetch)
+		firstDone <- err
+	}()
+	<-started
+
+	waiterDone := make(chan struct{})
+	var obj *IconikObject
+	var err error
+	go func() {
+		obj, err = cc.get(context.Background(), "a", fetch)
+		close(waiterDone)
+	}()
+	time.Sleep(time.Millisecond)
+	cancel()
+	if err := <-firstDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled get(a) got %v; wanted context.Canceled", err)
+	}
+	<-waiterDone
+	if err != nil || obj == nil || obj.Id != "a" {
+		t.Errorf("waiting get(a) got %v, %v; wanted the collection", obj, err)
+	}
+}
+
+// ancestorServer answers searches with matches and collection GETs from
+// parents, counting GETs per collection.
+func ancestorServer(t *testing.T, matches []IconikObject, parents map[string]IconikObject, gets map[string]int) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/search/v1/search/" {
+			json.NewEncoder(w).Encode(SearchResponse{Objects: matches, Pages: 1})
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/assets/v1/collections/")
+		mu.Lock()
+		gets[id]++
+		mu.Unlock()
+		obj, ok := parents[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(obj)
+	}
+}
+
+func TestIClient_GetCollectionIDsSharesAncestors(t *testing.T) {
+	parents := map[string]IconikObject{
+		"root": {Id: "root", Title: "Root"},
+		"mid":  {Id: "mid", Title: "Mid", InCollections: []string{"root"}},
+		"x":    {Id: "x", Title: "X", InCollections: []string{"mid"}},
+		"y":    {Id: "y", Title: "Y", InCollections: []string{"mid"}},
+	}
+	matches := []IconikObject{
+		{Id: "m1", Title: "Week1", InCollections: []string{"x"}},
+		{Id: "m2", Title: "Week1", InCollections: []string{"y"}},
+		{Id: "m3", Title: "Week1", InCollections: []string{"x", "gone"}},
+	}
+	gets := map[string]int{}
+	_, client := newTestServer(t, ancestorServer(t, matches, parents, gets), WithCollectionCache(NewCollectionCache(0, 0)))
+
+	got, err := client.GetCollectionIDs("Week1")
+	if err != nil {
+		t.Fatalf("GetCollectionIDs(Week1) got error %v", err)
+	}
+	want := []string{"Root/Mid/X/Week1 m1", "Root/Mid/Y/Week1 m2", "Root/Mid/X/Week1 m3"}
+	if len(got) != len(want) {
+		t.Fatalf("GetCollectionIDs(Week1) got %d results; wanted %d", len(got), len(want))
+	}
+	for i, r := range got {
+		if r.Path+" "+r.CollectionID != want[i] {
+			t.Errorf("GetCollectionIDs(Week1)[%d] got %s %s; wanted %s", i, r.Path, r.CollectionID, want[i])
+		}
+	}
+	for id, n := range gets {
+		if n != 1 {
+			t.Errorf("collection %s fetched %d times; wanted 1", id, n)
+		}
+	}
+
+	if _, err := client.GetCollectionIDs("Week1"); err != nil {
+		t.Fatalf("GetCollectionIDs(Week1) again got error %v", err)
+	}
+	for id, n := range gets {
+		if n != 1 {
+			t.Errorf("collection %s fetched %d times after a cached call; wanted 1", id, n)
+		}
+	}
+}
+
+func TestIClient_GetCollectionIDsDetectsCycles(t *testing.T) {
+	parents := map[string]IconikObject{
+		"a": {Id: "a", Title: "A", InCollections: []string{"b"}},
+		"b": {Id: "b", Title: "B", InCollections: []string{"a"}},
+	}
+	matches := []IconikObject{{Id: "m", Title: "M", InCollections: []string{"a"}}}
+	_, client := newTestServer(t, ancestorServer(t, matches, parents, map[string]int{}))
+
+	if _, err := client.GetCollectionIDs("M"); !errors.Is(err, ErrCollectionCycle) {
+		t.Errorf("GetCollectionIDs(M) got %v; wanted ErrCollectionCycle", err)
+	}
+}
@@ -0,0 +1,373 @@
+package iconik
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const collectionContentsEndpointTemplate = "assets/v1/collections/%s/contents/"
+
+// Object types of the items in a collection.
+const (
+	ObjectTypeAsset      = "assets"
+	ObjectTypeCollection = "collections"
+)
+
+// SkipCollection can be returned by a WalkFunc to skip the contents of the
+// collection it was called for. Returned for an asset, it is ignored.
+var SkipCollection = errors.New("skip this collection")
+
+// CollectionEntry is an item found by WalkCollection.
+type CollectionEntry struct {
+	Object IconikObject
+	// Path is the slash-separated titles from the walked collection down to
+	// the item, excluding the walked collection itself.
+	Path string
+	// Depth is 1 for the direct contents of the walked collection.
+	Depth int
+}
+
+// WalkFunc is called by WalkCollection for each item. Returning
+// SkipCollection skips a sub-collection's contents; any other error stops
+// the walk and is returned by WalkCollection.
+type WalkFunc func(entry CollectionEntry) error
+
+// GetCollection fetches the collection with the given ID. A missing
+// collection is reported as an error matching ErrNotFound.
+func (c *IClient) GetCollection(ctx context.Context, collectionID string) (*Collection, error) {
+	endpoint := fmt.Sprintf(collectionEndpointTemplate, url.PathEscape(collectionID))
+	c.log().Debug("GetCollection", F("endpoint", endpoint))
+	return doJSON[Collection](ctx, c, apiCall{method: http.MethodGet, path: endpoint})
+}
+
+// ListCollectionContents returns one page of the assets and sub-collections
+// directly inside a collection. Pages are numbered from 1; perPage below
+// one uses the server default.
+func (c *IClient) ListCollectionContents(ctx context.Context, collectionID string, page, perPage int) (*CollectionContentsPage, error) {
+	endpoint := fmt.Sprintf(collectionContentsEndpointTemplate, url.PathEscape(collectionID))
+	query := url.Values{}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if perPage > 0 {
+		query.Set("per_page", strconv.Itoa(perPage))
+	}
+	c.log().Debug("ListCollectionContents", F("endpoint", endpoint), F("page", page))
+	return doJSON[CollectionContentsPage](ctx, c, apiCall{method: http.MethodGet, path: endpoint, query: query})
+}
+
+// WalkCollection calls fn for every item in a collection and, depth first,
+// in its sub-collections. Items deeper than maxDepth are not visited; a
+// maxDepth below one means no limit. Each sub-collection is entered at
+// most once, so cycles in the collection graph do not loop forever.
+func (c *IClient) WalkCollection(ctx context.Context, collectionID string, maxDepth int, fn WalkFunc) error {
+	visited := map[string]bool{collectionID: true}
+	return c.walkCollection(ctx, collectionID, "", 1, maxDepth, visited, fn)
+}
+
+func (c *IClient) walkCollection(ctx context.Context, collectionID, path string, depth, maxDepth int, visited map[string]bool, fn WalkFunc) error {
+	if maxDepth > 0 && depth > maxDepth {
+		return nil
+	}
+	for page := 1; ; page++ {
+		contents, err := c.ListCollectionContents(ctx, collectionID, page, defaultSearchPerPage)
+		if err != nil {
+			return fmt.Errorf("listing collection %s: %w", collectionID, err)
+		}
+		for _, obj := range contents.Objects {
+			entry := CollectionEntry{Object: obj, Path: obj.Title, Depth: depth}
+			if path != "" {
+				entry.Path = path + "/" + obj.Title
+			}
+			err := fn(entry)
+			isCollection := obj.ObjectType == ObjectTypeCollection
+			if err == SkipCollection && isCollection {
+				continue
+			}
+			if err != nil && err != SkipCollection {
+				return err
+			}
+			if !isCollection || visited[obj.Id] {
+				continue
+			}
+			visited[obj.Id] = true
+			if err := c.walkCollection(ctx, obj.Id, entry.Path, depth+1, maxDepth, visited, fn); err != nil {
+				return err
+			}
+		}
+		if page >= contents.Pages || len(contents.Objects) == 0 {
+			return nil
+		}
+	}
+}
+
+// ErrAmbiguousPath is matched by errors.Is when a collection path matches
+// more than one collection.
+var ErrAmbiguousPath = errors.New("iconik: ambiguous collection path")
+
+// splitCollectionPath splits a slash-separated collection path into its
+// titles, ignoring leading, trailing and repeated slashes.
+func splitCollectionPath(path string) ([]string, error) {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	if len(segments) == 0 {
+		return nil, &IError{Errors: []string{fmt.Sprintf("empty collection path %q", path)}}
+	}
+	return segments, nil
+}
+
+// GetCollectionByPath returns the ID of the collection at a slash-separated
+// path of titles starting from a top-level collection, e.g.
+// "Teaching/2024/Fall". Titles must match exactly. A path that does not
+// exist is reported as an error matching ErrNotFound, and one that matches
+// several collections as an error matching ErrAmbiguousPath.
+func (c *IClient) GetCollectionByPath(ctx context.Context, path string) (string, error) {
+	segments, err := splitCollectionPath(path)
+	if err != nil {
+		return "", err
+	}
+	ids, err := c.resolveCollectionPath(ctx, segments)
+	if err != nil {
+		return "", err
+	}
+	if len(ids) < len(segments) {
+		return "", fmt.Errorf("%w: collection %q", ErrNotFound, strings.Join(segments[:len(ids)+1], "/"))
+	}
+	return ids[len(ids)-1], nil
+}
+
+// EnsureCollectionPath is like GetCollectionByPath but creates any missing
+// collections along the path, like mkdir -p, and returns the ID of the
+// last one.
+func (c *IClient) EnsureCollectionPath(ctx context.Context, path string) (string, error) {
+	segments, err := splitCollectionPath(path)
+	if err != nil {
+		return "", err
+	}
+	ids, err := c.resolveCollectionPath(ctx, segments)
+	if err != nil {
+		return "", err
+	}
+	parentID := ""
+	if len(ids) > 0 {
+		parentID = ids[len(ids)-1]
+	}
+	for _, title := range segments[len(ids):] {
+		parentID, err = c.CreateCollectionContext(ctx, title, parentID)
+		if err != nil {
+			return "", err
+		}
+		c.log().Info("created collection", F("title", title), F("collection_id", parentID))
+	}
+	return parentID, nil
+}
+
+// resolveCollectionPath returns the IDs of the collections along the
+// longest existing prefix of segments.
+func (c *IClient) resolveCollectionPath(ctx context.Context, segments []string) ([]string, error) {
+	top, err := c.topLevelCollection(ctx, segments[0])
+	if err != nil || top == "" {
+		return nil, err
+	}
+	ids := []string{top}
+	for i, title := range segments[1:] {
+		child, err := c.childCollection(ctx, ids[i], title)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %q: %w", strings.Join(segments[:i+2], "/"), err)
+		}
+		if child == "" {
+			break
+		}
+		ids = append(ids, child)
+	}
+	return ids, nil
+}
+
+// topLevelCollection returns the ID of the top-level collection with the
+// given title, or "" if there is none.
+func (c *IClient) topLevelCollection(ctx context.Context, title string) (string, error) {
+	q := NewSearchQuery().DocTypes(DocTypeCollections).Where(Match("title", title))
+	it := c.SearchIter(ctx, q)
+	var found []string
+	for it.Next() {
+		obj := it.Object()
+		if obj.Title == title && len(obj.InCollections) == 0 && obj.Status != "DELETED" {
+			found = append(found, obj.Id)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return "", err
+	}
+	return onlyCollection(title, found)
+}
+
+// childCollection returns the ID of the sub-collection of parentID with the
+// given title, or "" if there is none.
+func (c *IClient) childCollection(ctx context.Context, parentID, title string) (string, error) {
+	var found []string
+	for page := 1; ; page++ {
+		contents, err := c.ListCollectionContents(ctx, parentID, page, defaultSearchPerPage)
+		if err != nil {
+			return "", err
+		}
+		for _, obj := range contents.Objects {
+			if obj.ObjectType == ObjectTypeCollection && obj.Title == title && obj.Status != "DELETED" {
+				found = append(found, obj.Id)
+			}
+		}
+		if page >= contents.Pages || len(contents.Objects) == 0 {
+			break
+		}
+	}
+	return onlyCollection(title, found)
+}
+
+func onlyCollection(title string, ids []string) (string, error) {
+	switch len(ids) {
+	case 0:
+		return "", nil
+	case 1:
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("%w: %d collections named %q: %s", ErrAmbiguousPath, len(ids), title, strings.Join(ids, ", "))
+}
+
+const collectionContentEndpointTemplate = "assets/v1/collections/%s/contents/%s/%s/"
+
+// ErrCollectionNotEmpty is matched by errors.Is when DeleteCollection is
+// asked to delete a collection that still has contents without deleting
+// them too.
+var ErrCollectionNotEmpty = errors.New("iconik: collection is not empty")
+
+// RenameCollection changes the title of a collection.
+func (c *IClient) RenameCollection(ctx context.Context, collectionID, title string) error {
+	return c.patchCollection(ctx, "RenameCollection", collectionID, map[string]string{"title": title})
+}
+
+// MoveCollection makes a collection a sub-collection of newParentID.
+func (c *IClient) MoveCollection(ctx context.Context, collectionID, newParentID string) error {
+	return c.patchCollection(ctx, "MoveCollection", collectionID, map[string]string{"parent_id": newParentID})
+}
+
+func (c *IClient) patchCollection(ctx context.Context, op, collectionID string, body map[string]string) error {
+	endpoint := fmt.Sprintf(collectionEndpointTemplate, url.PathEscape(collectionID))
+	c.log().Debug(op, F("endpoint", endpoint))
+	_, err := c.send(ctx, apiCall{method: http.MethodPatch, path: endpoint, body: body})
+	c.invalidateCollection(collectionID)
+	return err
+}
+
+// DeleteCollection deletes a collection. If it has contents, they are
+// deleted first when recursive is set: sub-collections recursively, and
+// assets as by DeleteAsset without purging. Assets that also belong to
+// collections outside the deleted tree are only removed from it. Otherwise
+// a collection with contents is left alone and an error matching
+// ErrCollectionNotEmpty is returned.
+func (c *IClient) DeleteCollection(ctx context.Context, collectionID string, recursive bool) error {
+	contents, err := c.ListCollectionContents(ctx, collectionID, 1, 1)
+	if err != nil {
+		return err
+	}
+	if len(contents.Objects) > 0 {
+		if !recursive {
+			return fmt.Errorf("%w: %s", ErrCollectionNotEmpty, collectionID)
+		}
+		if err := c.deleteCollectionContents(ctx, collectionID); err != nil {
+			return err
+		}
+	}
+	endpoint := fmt.Sprintf(collectionEndpointTemplate, url.PathEscape(collectionID))
+	c.log().Debug("DeleteCollection", F("endpoint", endpoint))
+	_, err = c.send(ctx, apiCall{method: http.MethodDelete, path: endpoint})
+	c.invalidateCollection(collectionID)
+	return err
+}
+
+// deleteCollectionContents deletes everything inside a collection. The
+// contents are listed in full first, since deleting while paging would
+// shift later pages.
+func (c *IClient) deleteCollectionContents(ctx context.Context, collectionID string) error {
+	endpoint := fmt.Sprintf(collectionContentsEndpointTemplate, url.PathEscape(collectionID))
+	objects, err := listAll[IconikObject](ctx, c, apiCall{method: http.MethodGet, path: endpoint})
+	if err != nil {
+		return fmt.Errorf("listing collection %s: %w", collectionID, err)
+	}
+	for _, obj := range objects {
+		switch obj.ObjectType {
+		case ObjectTypeCollection:
+			err = c.DeleteCollection(ctx, obj.Id, true)
+		case ObjectTypeAsset:
+			err = c.deleteCollectionAsset(ctx, collectionID, obj)
+		default:
+			err = fmt.Errorf("unknown object type %q", obj.ObjectType)
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("deleting %s from collection %s: %w", obj.Id, collectionID, err)
+		}
+	}
+	return nil
+}
+
+// deleteCollectionAsset deletes an asset listed in a collection that is
+// being deleted, or only removes it from the collection if it also belongs
+// to another one. The listing was taken before any of the tree was deleted
+// and may name collections the asset has since been removed from, so a
+// shared asset is fetched again before it is kept.
+func (c *IClient) deleteCollectionAsset(ctx context.Context, collectionID string, obj IconikObject) error {
+	if inOtherCollection(obj.InCollections, collectionID) {
+		asset, err := c.GetAsset(ctx, obj.Id)
+		if err != nil {
+			return err
+		}
+		if inOtherCollection(asset.InCollections, collectionID) {
+			return c.RemoveFromCollection(ctx, collectionID, obj.Id, ObjectTypeAsset)
+		}
+	}
+	return c.DeleteAsset(ctx, obj.Id, false)
+}
+
+// inOtherCollection reports whether collections holds any collection
+// besides collectionID.
+func inOtherCollection(collections []string, collectionID string) bool {
+	for _, id := range collections {
+		if id != collectionID {
+			return true
+		}
+	}
+	return false
+}
+
+// AddToCollection adds an asset or sub-collection to a collection.
+// objectType is ObjectTypeAsset or ObjectTypeCollection.
+func (c *IClient) AddToCollection(ctx context.Context, collectionID, objectID, objectType string) error {
+	endpoint := fmt.Sprintf(collectionContentsEndpointTemplate, url.PathEscape(collectionID))
+	body := map[string]string{"object_id": objectID, "object_type": objectType}
+	c.log().Debug("AddToCollection", F("endpoint", endpoint), F("object_id", objectID), F("object_type", objectType))
+	_, err := c.send(ctx, apiCall{method: http.MethodPost, path: endpoint, body: body})
+	if objectType == ObjectTypeCollection {
+		c.invalidateCollection(objectID)
+	}
+	return err
+}
+
+// RemoveFromCollection removes an asset or sub-collection from a
+// collection without deleting it. objectType is ObjectTypeAsset or
+// ObjectTypeCollection.
+func (c *IClient) RemoveFromCollection(ctx context.Context, collectionID, objectID, objectType string) error {
+	endpoint := fmt.Sprintf(collectionContentEndpointTemplate, url.PathEscape(collectionID), url.PathEscape(objectType), url.PathEscape(objectID))
+	c.log().Debug("RemoveFromCollection", F("endpoint", endpoint))
+	_, err := c.send(ctx, apiCall{method: http.MethodDelete, path: endpoint})
+	if objectType == ObjectTypeCollection {
+		c.invalidateCollection(objectID)
+	}
+	return err
+}
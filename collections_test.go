@@ -0,0 +1,282 @@
+package iconik
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// collectionTree serves the contents of collections from tree, one object
+// per page so that pagination is exercised.
+func collectionTree(t *testing.T, tree map[string][]IconikObject) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) != 5 || parts[4] != "contents" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			return
+		}
+		objects := tree[parts[3]]
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		resp := CollectionContentsPage{Page: page, Pages: len(objects)}
+		if page >= 1 && page <= len(objects) {
+			resp.Objects = objects[page-1 : page]
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func TestIClient_WalkCollection(t *testing.T) {
+	asset := func(id string) IconikObject { return IconikObject{Id: id, Title: id, ObjectType: ObjectTypeAsset} }
+	col := func(id string) IconikObject { return IconikObject{Id: id, Title: id, ObjectType: ObjectTypeCollection} }
+	tree := map[string][]IconikObject{
+		"root": {asset("a1"), col("c1"), col("c2")},
+		"c1":   {asset("a2"), col("c3")},
+		"c2":   {asset("a3")},
+		"c3":   {asset("a4"), col("root")},
+	}
+	_, client := newTestServer(t, collectionTree(t, tree))
+
+	walk := func(maxDepth int, skip string) []string {
+		var visited []string
+		err := client.WalkCollection(context.Background(), "root", maxDepth, func(e CollectionEntry) error {
+			visited = append(visited, e.Path+"@"+strconv.Itoa(e.Depth))
+			if e.Object.Id == skip {
+				return SkipCollection
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WalkCollection(root, %d) got error %v", maxDepth, err)
+		}
+		return visited
+	}
+
+	want := []string{"a1@1", "c1@1", "c1/a2@2", "c1/c3@2", "c1/c3/a4@3", "c1/c3/root@3", "c2@1", "c2/a3@2"}
+	if got := walk(0, ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkCollection(root, 0) got %v; wanted %v", got, want)
+	}
+	want = []string{"a1@1", "c1@1", "c1/a2@2", "c1/c3@2", "c2@1", "c2/a3@2"}
+	if got := walk(2, ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkCollection(root, 2) got %v; wanted %v", got, want)
+	}
+	want = []string{"a1@1", "c1@1", "c2@1", "c2/a3@2"}
+	if got := walk(0, "c1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkCollection(root, 0) skipping c1 got %v; wanted %v", got, want)
+	}
+}
+
+func TestIClient_WalkCollectionStops(t *testing.T) {
+	_, client := newTestServer(t, collectionTree(t, map[string][]IconikObject{
+		"root": {{Id: "a1", ObjectType: ObjectTypeAsset}, {Id: "a2", ObjectType: ObjectTypeAsset}},
+	}))
+
+	stop := errors.New("stop")
+	calls := 0
+	err := client.WalkCollection(context.Background(), "root", 0, func(CollectionEntry) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("WalkCollection() got %v after %d calls; wanted stop after 1", err, calls)
+	}
+}
+
+func TestIClient_GetCollection(t *testing.T) {
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/assets/v1/collections/c1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"id":"c1","title":"Lectures","parent_id":"root","in_collections":["root"],
+			"status":"ACTIVE","date_created":"2022-03-04T05:06:07Z"}`))
+	})
+
+	col, err := client.GetCollection(context.Background(), "c1")
+	if err != nil {
+		t.Fatalf("GetCollection(c1) got error %v", err)
+	}
+	if col.Title != "Lectures" || col.ParentID != "root" || col.DateCreated.IsZero() {
+		t.Errorf("GetCollection(c1) got %+v", col)
+	}
+	if _, err := client.GetCollection(context.Background(), "c2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetCollection(c2) got %v; wanted ErrNotFound", err)
+	}
+}
+
+// pathServer serves a small collection tree for path resolution and records
+// created collections.
+func pathServer(t *testing.T, created *[]string) http.HandlerFunc {
+	col := func(id, title string, parents ...string) IconikObject {
+		return IconikObject{Id: id, Title: title, ObjectType: ObjectTypeCollection, InCollections: parents}
+	}
+	contents := map[string][]IconikObject{
+		"teaching": {col("t2024", "2024", "teaching"), {Id: "a1", Title: "2024", ObjectType: ObjectTypeAsset}},
+		"t2024":    {col("fall1", "Fall", "t2024"), col("fall2", "Fall", "t2024"), col("spring", "Spring", "t2024")},
+	}
+	next := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/search/v1/search/":
+			json.NewEncoder(w).Encode(SearchResponse{Pages: 1, Objects: []IconikObject{
+				col("teaching", "Teaching"),
+				col("nested", "Teaching", "other"),
+				col("similar", "Teaching Lab"),
+			}})
+		case r.URL.Path == "/assets/v1/collections/" && r.Method == http.MethodPost:
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			next++
+			id := "new" + strconv.Itoa(next)
+			*created = append(*created, body["parent_id"]+">"+body["title"]+"="+id)
+			w.Write([]byte(`{"id":"` + id + `"}`))
+		case strings.HasSuffix(r.URL.Path, "/contents/"):
+			id := strings.Split(r.URL.Path, "/")[4]
+			json.NewEncoder(w).Encode(CollectionContentsPage{Pages: 1, Objects: contents[id]})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}
+}
+
+func TestIClient_GetCollectionByPath(t *testing.T) {
+	var created []string
+	_, client := newTestServer(t, pathServer(t, &created))
+	ctx := context.Background()
+
+	if id, err := client.GetCollectionByPath(ctx, "/Teaching/2024/Spring/"); err != nil || id != "spring" {
+		t.Errorf("GetCollectionByPath(Teaching/2024/Spring) got %q, %v; wanted spring", id, err)
+	}
+	if _, err := client.GetCollectionByPath(ctx, "Teaching/2024/Winter"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetCollectionByPath(Teaching/2024/Winter) got %v; wanted ErrNotFound", err)
+	}
+	if _, err := client.GetCollectionByPath(ctx, "Teaching/2024/Fall"); !errors.Is(err, ErrAmbiguousPath) {
+		t.Errorf("GetCollectionByPath(Teaching/2024/Fall) got %v; wanted ErrAmbiguousPath", err)
+	}
+	if _, err := client.GetCollectionByPath(ctx, "//"); err == nil {
+		t.Errorf("GetCollectionByPath(//) got nil error")
+	}
+	if len(created) != 0 {
+		t.Errorf("GetCollectionByPath() created %v; wanted nothing", created)
+	}
+}
+
+func TestIClient_EnsureCollectionPath(t *testing.T) {
+	var created []string
+	_, client := newTestServer(t, pathServer(t, &created))
+	ctx := context.Background()
+
+	if id, err := client.EnsureCollectionPath(ctx, "Teaching/2024"); err != nil || id != "t2024" {
+		t.Errorf("EnsureCollectionPath(Teaching/2024) got %q, %v; wanted t2024", id, err)
+	}
+	id, err := client.EnsureCollectionPath(ctx, "Teaching/2024/Summer/Week 1")
+	if err != nil || id != "new2" {
+		t.Errorf("EnsureCollectionPath(Teaching/2024/Summer/Week 1) got %q, %v; wanted new2", id, err)
+	}
+	want := []string{"t2024>Summer=new1", "new1>Week 1=new2"}
+	if !reflect.DeepEqual(created, want) {
+		t.Errorf("EnsureCollectionPath() created %v; wanted %v", created, want)
+	}
+}
+
+func TestIClient_CollectionMutations(t *testing.T) {
+	var requests []string
+	cache := NewCollectionCache(0, 0)
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(b)))
+	}, WithCollectionCache(cache))
+	ctx := context.Background()
+	cache.add("c1", &IconikObject{Id: "c1"})
+	cache.add("c2", &IconikObject{Id: "c2"})
+
+	if err := client.RenameCollection(ctx, "c1", "Fall"); err != nil {
+		t.Errorf("RenameCollection() got error %v", err)
+	}
+	if err := client.MoveCollection(ctx, "c1", "p2"); err != nil {
+		t.Errorf("MoveCollection() got error %v", err)
+	}
+	if err := client.AddToCollection(ctx, "p2", "a1", ObjectTypeAsset); err != nil {
+		t.Errorf("AddToCollection() got error %v", err)
+	}
+	if err := client.RemoveFromCollection(ctx, "p1", "c2", ObjectTypeCollection); err != nil {
+		t.Errorf("RemoveFromCollection() got error %v", err)
+	}
+	want := []string{
+		`PATCH /assets/v1/collections/c1 {"title":"Fall"}`,
+		`PATCH /assets/v1/collections/c1 {"parent_id":"p2"}`,
+		`POST /assets/v1/collections/p2/contents/ {"object_id":"a1","object_type":"assets"}`,
+		`DELETE /assets/v1/collections/p1/contents/collections/c2/`,
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests got %q; wanted %q", requests, want)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("cache holds %d collections after changing them; wanted 0", cache.Len())
+	}
+}
+
+func TestIClient_DeleteCollection(t *testing.T) {
+	// "both" is in top and sub. sub is emptied first and removes it, so
+	// top's listing of it is stale by the time top gets to it.
+	in := map[string][]string{"both": {"top", "sub"}, "shared": {"sub", "other"}}
+	tree := map[string][]IconikObject{
+		"top": {
+			{Id: "a1", ObjectType: ObjectTypeAsset, InCollections: []string{"top"}},
+			{Id: "sub", ObjectType: ObjectTypeCollection},
+			{Id: "both", ObjectType: ObjectTypeAsset, InCollections: in["both"]},
+		},
+		"sub": {
+			{Id: "a2", ObjectType: ObjectTypeAsset},
+			{Id: "shared", ObjectType: ObjectTypeAsset, InCollections: in["shared"]},
+			{Id: "both", ObjectType: ObjectTypeAsset, InCollections: in["both"]},
+		},
+	}
+	var deleted []string
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case r.Method == http.MethodGet && len(parts) == 5 && parts[4] == "contents":
+			json.NewEncoder(w).Encode(CollectionContentsPage{Pages: 1, Objects: tree[parts[3]]})
+		case r.Method == http.MethodGet && len(parts) == 4 && parts[2] == "assets":
+			json.NewEncoder(w).Encode(Asset{Id: parts[3], InCollections: in[parts[3]]})
+		case r.Method == http.MethodDelete && len(parts) == 7:
+			deleted = append(deleted, parts[3]+"/"+parts[6])
+			var kept []string
+			for _, id := range in[parts[6]] {
+				if id != parts[3] {
+					kept = append(kept, id)
+				}
+			}
+			in[parts[6]] = kept
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, parts[2]+"/"+parts[3])
+			tree[parts[3]] = nil
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+	ctx := context.Background()
+
+	if err := client.DeleteCollection(ctx, "top", false); !errors.Is(err, ErrCollectionNotEmpty) {
+		t.Errorf("DeleteCollection(top, false) got %v; wanted ErrCollectionNotEmpty", err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("DeleteCollection(top, false) deleted %v; wanted nothing", deleted)
+	}
+
+	if err := client.DeleteCollection(ctx, "top", true); err != nil {
+		t.Fatalf("DeleteCollection(top, true) got error %v", err)
+	}
+	want := []string{"assets/a1", "assets/a2", "sub/shared", "sub/both", "collections/sub", "assets/both", "collections/top"}
+	if !reflect.DeepEqual(deleted, want) {
+		t.Errorf("DeleteCollection(top, true) deleted %v; wanted %v", deleted, want)
+	}
+}
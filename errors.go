@@ -0,0 +1,91 @@
+package iconik
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors matched by APIError via errors.Is, e.g.
+//
+//	if errors.Is(err, iconik.ErrNotFound) { ... }
+var (
+	ErrUnauthorized = errors.New("iconik: unauthorized")
+	ErrNotFound     = errors.New("iconik: not found")
+	ErrConflict     = errors.New("iconik: conflict")
+	ErrRateLimited  = errors.New("iconik: rate limited")
+)
+
+// APIError is returned when the Iconik API answers with an unexpected HTTP
+// status.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Endpoint   string
+	// Errors is the error list from Iconik's {"errors": [...]} body. It is
+	// empty if the body had another shape.
+	Errors []string
+	// Body is the raw response body.
+	Body []byte
+	// RequestID is the server's ID for the request, if it returned one.
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%v", e.Errors)
+	if len(e.Errors) == 0 {
+		msg = string(e.Body)
+	}
+	if e.Method == "" {
+		return fmt.Sprintf("HTTP %d: %s", e.StatusCode, msg)
+	}
+	return fmt.Sprintf("%s %s: HTTP %d: %s", e.Method, e.Endpoint, e.StatusCode, msg)
+}
+
+// Is reports whether e corresponds to one of the sentinel errors.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// As lets callers that still match on *IError keep working.
+func (e *APIError) As(target interface{}) bool {
+	if t, ok := target.(**IError); ok {
+		errs := e.Errors
+		if len(errs) == 0 {
+			errs = []string{fmt.Sprintf("HTTP %d: %s", e.StatusCode, string(e.Body))}
+		}
+		*t = &IError{Errors: errs}
+		return true
+	}
+	return false
+}
+
+// apiErrorFromResponse builds an APIError for a response whose body has
+// already been read.
+func apiErrorFromResponse(resp *http.Response, body []byte) error {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       body,
+		RequestID:  requestID(resp),
+	}
+	if resp.Request != nil {
+		apiErr.Method = resp.Request.Method
+		apiErr.Endpoint = resp.Request.URL.Path
+	}
+	iErr := IError{}
+	if err := json.Unmarshal(body, &iErr); err == nil {
+		apiErr.Errors = iErr.Errors
+	}
+	return apiErr
+}
@@ -0,0 +1,61 @@
+package iconik
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestAPIError_Sentinels(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusConflict, ErrConflict},
+		{http.StatusTooManyRequests, ErrRateLimited},
+	}
+	sentinels := []error{ErrUnauthorized, ErrNotFound, ErrConflict, ErrRateLimited}
+	for _, tt := range tests {
+		err := error(&APIError{StatusCode: tt.status})
+		for _, s := range sentinels {
+			if got := errors.Is(err, s); got != (s == tt.want) {
+				t.Errorf("errors.Is(HTTP %d, %v) got %v; wanted %v", tt.status, s, got, s == tt.want)
+			}
+		}
+	}
+}
+
+func TestIClient_APIError(t *testing.T) {
+	_, client := newTestServer(t, func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-Request-Id", "req-123")
+		rw.WriteHeader(http.StatusNotFound)
+		rw.Write([]byte(`{"errors": ["Asset not found"]}`))
+	})
+	_, err := client.GenerateSignedFileUrl("testAssetId")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GenerateSignedFileUrl() got %v; wanted ErrNotFound", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("GenerateSignedFileUrl() got %T; wanted *APIError", err)
+	}
+	if apiErr.Method != http.MethodGet || apiErr.Endpoint != "/files/v1/assets/testAssetId/files" ||
+		apiErr.RequestID != "req-123" || len(apiErr.Errors) != 1 || apiErr.Errors[0] != "Asset not found" {
+		t.Errorf("GenerateSignedFileUrl() got %+v; wanted GET of the files endpoint with request ID and error list", apiErr)
+	}
+	var iErr *IError
+	if !errors.As(err, &iErr) || iErr.Errors[0] != "Asset not found" {
+		t.Errorf("errors.As(*IError) got %v; wanted the Iconik error list", iErr)
+	}
+}
+
+func TestIClient_GenerateSignedFileUrlNoFiles(t *testing.T) {
+	_, client := newTestServer(t, func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"objects": []}`))
+	})
+	if _, err := client.GenerateSignedFileUrl("testAssetId"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GenerateSignedFileUrl() of an asset without files got %v; wanted ErrNotFound", err)
+	}
+}
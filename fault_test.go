@@ -0,0 +1,155 @@
+package iconik
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// callAllMethods invokes every public IClient method that talks to the API
+// and reports the first panic as a test failure. Errors are expected and
+// ignored: the point is that malformed or missing responses are reported
+// as errors rather than crashing the process.
+func callAllMethods(t *testing.T, client *IClient) {
+	t.Helper()
+	calls := map[string]func() error{
+		"SearchWithTitleAndTag": func() error { _, err := client.SearchWithTitleAndTag("title", "tag", false); return err },
+		"GetKeyframeUrl":        func() error { _, err := client.GetKeyframeUrl("asset"); return err },
+		"GenerateSignedProxyUrl": func() error {
+			_, err := client.GenerateSignedProxyUrl("asset")
+			return err
+		},
+		"GenerateSignedFileUrl": func() error { _, err := client.GenerateSignedFileUrl("asset"); return err },
+		"GetCollectionIDs":      func() error { _, err := client.GetCollectionIDs("collection"); return err },
+		"PostAssetID":           func() error { _, err := client.PostAssetID("collection", "title"); return err },
+		"MakeStorageID":         func() error { _, err := client.MakeStorageID(); return err },
+		"MakeFormatID":          func() error { _, err := client.MakeFormatID("user", "asset", "video/mp4"); return err },
+		"MakeFileSetID": func() error {
+			_, err := client.MakeFileSetID("asset", "format", "storage", "title", "/")
+			return err
+		},
+		"GetUploadUrl": func() error {
+			_, err := client.GetUploadUrl("asset", "title", "/", "format", "fileset", "storage", "date", 10)
+			return err
+		},
+		"GetMultipartStartUrl": func() error { return client.GetMultipartStartUrl(&NewAssetUpload{}) },
+		"PostStartOfJob":       func() error { _, err := client.PostStartOfJob("asset", "title"); return err },
+		"MakeNewAsset": func() error {
+			_, err := client.MakeNewAsset("collection", "file", "title", "/", "video/mp4", 2*MULTIPART_FILESIZE_THRESHOLD, time.Now())
+			return err
+		},
+		"FinishUpload": func() error {
+			return client.FinishUpload(&NewAssetUpload{MultipartFileID: "multi", Sha1List: []string{"sha"}})
+		},
+		"CreateCollection": func() error { _, err := client.CreateCollection("title", "parent"); return err },
+		"GetAssetFileSize": func() error { _, err := client.GetAssetFileSize("asset"); return err },
+	}
+	for name, call := range calls {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s panicked: %v", name, r)
+				}
+			}()
+			call()
+		}()
+	}
+}
+
+func TestIClient_NoPanicOnMalformedResponses(t *testing.T) {
+	bodies := map[string]string{
+		"malformed JSON":     `{"objects": [`,
+		"wrong types":        `{"objects": "nope", "id": 42, "pages": "x"}`,
+		"empty object list":  `{"objects": []}`,
+		"null":               `null`,
+		"empty body":         ``,
+		"errors not strings": `{"errors": [1, 2]}`,
+	}
+	for name, body := range bodies {
+		for _, status := range []int{http.StatusOK, http.StatusCreated, http.StatusBadRequest} {
+			body, status := body, status
+			t.Run(name, func(t *testing.T) {
+				_, client := newTestServer(t, func(rw http.ResponseWriter, req *http.Request) {
+					rw.WriteHeader(status)
+					rw.Write([]byte(body))
+				})
+				client.NoRetry = true
+				callAllMethods(t, client)
+			})
+		}
+	}
+}
+
+func TestIClient_NoPanicOnConnectionReset(t *testing.T) {
+	_, client := newTestServer(t, func(rw http.ResponseWriter, req *http.Request) {
+		conn, _, err := rw.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Fatalf("Hijack() got %v", err)
+		}
+		conn.Close()
+	})
+	client.NoRetry = true
+	callAllMethods(t, client)
+
+	if _, err := client.GenerateSignedFileUrl("asset"); err == nil {
+		t.Errorf("GenerateSignedFileUrl() on a reset connection got no error; wanted one")
+	}
+}
+
+func TestIClient_NoPanicOnTruncatedBody(t *testing.T) {
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(io.MultiReader(bytes.NewReader([]byte(`{"objects": [{"id"`)), errReader{io.ErrUnexpectedEOF})),
+			Request:    req,
+		}, nil
+	})
+	client := newTestClient(t, "", WithTransport(rt))
+	client.NoRetry = true
+	callAllMethods(t, client)
+
+	if _, err := client.GetAssetFileSize("asset"); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("GetAssetFileSize() got %v; wanted %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
+
+// maxFuzzCalls stops a fuzzed client after this many requests, so inputs
+// that describe endless pagination terminate.
+const maxFuzzCalls = 500
+
+func FuzzIClientResponses(f *testing.F) {
+	f.Add(200, []byte(`{"objects": [{"id": "a", "url": "https://test.com", "in_collections": ["a"]}], "pages": 2}`))
+	f.Add(201, []byte(`{"id": "a"}`))
+	f.Add(404, []byte(`{"errors": ["not found"]}`))
+	f.Add(200, []byte(`{"objects": []}`))
+	f.Fuzz(func(t *testing.T, status int, body []byte) {
+		if status < 100 || status > 999 {
+			return
+		}
+		var calls int32
+		rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if atomic.AddInt32(&calls, 1) > maxFuzzCalls {
+				return nil, context.DeadlineExceeded
+			}
+			return &http.Response{
+				StatusCode: status,
+				Header:     make(http.Header),
+				Body:       io.NopCloser(bytes.NewReader(body)),
+				Request:    req,
+			}, nil
+		})
+		client := newTestClient(t, "", WithTransport(rt))
+		client.NoRetry = true
+		callAllMethods(t, client)
+	})
+}
@@ -1,3 +1,3 @@
 module github.com/jzhang919/iconikclient2
 
-go 1.17
+go 1.18
@@ -0,0 +1,249 @@
+package iconik
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Level is the severity of a log message.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// Field is a key/value pair attached to a log message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F is shorthand for creating a Field.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger is a leveled, structured logger. IClient redacts credentials,
+// upload tokens and signed URL query strings from field values before
+// calling it, so implementations can forward fields as they are.
+//
+// Adapt your logging stack by implementing this interface, or use
+// NewStdLogger to write to a standard library *log.Logger.
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+}
+
+// StdLogger writes messages at or above a minimum level to a standard
+// library logger as "LEVEL msg key=value ...".
+type StdLogger struct {
+	l   *log.Logger
+	min Level
+}
+
+// NewStdLogger creates a Logger that writes to l, dropping messages below
+// min. A nil l writes to the standard logger.
+func NewStdLogger(l *log.Logger, min Level) *StdLogger {
+	if l == nil {
+		l = log.Default()
+	}
+	return &StdLogger{l: l, min: min}
+}
+
+func (s *StdLogger) Debug(msg string, fields ...Field) { s.output(LevelDebug, msg, fields) }
+func (s *StdLogger) Info(msg string, fields ...Field)  { s.output(LevelInfo, msg, fields) }
+func (s *StdLogger) Warn(msg string, fields ...Field)  { s.output(LevelWarn, msg, fields) }
+func (s *StdLogger) Error(msg string, fields ...Field) { s.output(LevelError, msg, fields) }
+
+func (s *StdLogger) output(level Level, msg string, fields []Field) {
+	if level < s.min {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for _, f := range fields {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		v := fmt.Sprint(f.Value)
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = strconv.Quote(v)
+		}
+		b.WriteString(v)
+	}
+	s.l.Output(3, b.String())
+}
+
+// nopLogger discards everything.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...Field) {}
+func (nopLogger) Info(string, ...Field)  {}
+func (nopLogger) Warn(string, ...Field)  {}
+func (nopLogger) Error(string, ...Field) {}
+
+// log returns the logger for the client's diagnostic output. Without a
+// configured Logger, output goes to the standard logger when Debug is set
+// and is discarded otherwise.
+func (c *IClient) log() Logger {
+	l := c.logger
+	if l == nil {
+		if !c.Debug {
+			return nopLogger{}
+		}
+		l = NewStdLogger(nil, LevelDebug)
+	}
+	return redactingLogger{l}
+}
+
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders are the request and response headers whose values must
+// never be logged.
+var sensitiveHeaders = map[string]bool{
+	"App-Id":        true,
+	"Auth-Token":    true,
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+var (
+	// sensitiveJSON matches JSON members holding upload credentials or
+	// tokens, whether their value is a string or a flat object.
+	sensitiveJSON = regexp.MustCompile(`"(upload_credentials|authorizationToken|authorization_token|upload_auth_token|auth_token|token|password|app_id)"\s*:\s*("(?:[^"\\]|\\.)*"|\{[^{}]*\})`)
+
+	// urlQuery matches the query string of any http(s) URL, which for
+	// Iconik and B2 signed URLs carries the signature.
+	urlQuery = regexp.MustCompile(`(https?://[^\s"'?]+)\?[^\s"']*`)
+)
+
+// redact removes credentials and signed URL query strings from s.
+func redact(s string) string {
+	s = sensitiveJSON.ReplaceAllString(s, `"$1":"`+redacted+`"`)
+	return urlQuery.ReplaceAllString(s, "$1?"+redacted)
+}
+
+// redactHeader returns a copy of h with sensitive values replaced.
+func redactHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, vs := range h {
+		if sensitiveHeaders[http.CanonicalHeaderKey(k)] {
+			out[k] = []string{redacted}
+			continue
+		}
+		out[k] = vs
+	}
+	return out
+}
+
+// redactingLogger scrubs field values before passing them on.
+type redactingLogger struct {
+	l Logger
+}
+
+func (r redactingLogger) Debug(msg string, fields ...Field) { r.l.Debug(msg, redactFields(fields)...) }
+func (r redactingLogger) Info(msg string, fields ...Field)  { r.l.Info(msg, redactFields(fields)...) }
+func (r redactingLogger) Warn(msg string, fields ...Field)  { r.l.Warn(msg, redactFields(fields)...) }
+func (r redactingLogger) Error(msg string, fields ...Field) { r.l.Error(msg, redactFields(fields)...) }
+
+func redactFields(fields []Field) []Field {
+	out := make([]Field, len(fields))
+	for i, f := range fields {
+		out[i] = Field{Key: f.Key, Value: redactValue(f.Key, f.Value)}
+	}
+	return out
+}
+
+func redactValue(key string, value interface{}) interface{} {
+	if sensitiveHeaders[http.CanonicalHeaderKey(key)] {
+		return redacted
+	}
+	switch v := value.(type) {
+	case string:
+		return redact(v)
+	case []byte:
+		return redact(string(v))
+	case http.Header:
+		return redactHeader(v)
+	case error:
+		return redact(v.Error())
+	case fmt.Stringer:
+		return redact(v.String())
+	}
+	return value
+}
+
+// requestID returns the ID the server assigned to a response, if any.
+func requestID(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+	for _, h := range []string{"X-Request-Id", "Request-Id", "X-Iconik-Request-Id"} {
+		if id := resp.Header.Get(h); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// logAttempt records the outcome of one HTTP round trip.
+func (c *IClient) logAttempt(req *http.Request, resp *http.Response, err error, attempt int, d time.Duration) {
+	fields := []Field{
+		F("method", req.Method),
+		F("endpoint", req.URL.Path),
+		F("attempt", attempt),
+		F("duration", d),
+	}
+	if err != nil {
+		c.log().Warn("request failed", append(fields, F("error", err))...)
+		return
+	}
+	fields = append(fields, F("status", resp.StatusCode))
+	if id := requestID(resp); id != "" {
+		fields = append(fields, F("request_id", id))
+	}
+	if resp.StatusCode >= 400 {
+		c.log().Warn("request returned an error status", fields...)
+		return
+	}
+	c.log().Debug("request", fields...)
+}
+
+// logResponse records a response body at debug level.
+func (c *IClient) logResponse(resp *http.Response, body []byte) {
+	fields := []Field{F("status", resp.StatusCode)}
+	if resp.Request != nil {
+		fields = append(fields, F("method", resp.Request.Method), F("endpoint", resp.Request.URL.Path))
+	}
+	if id := requestID(resp); id != "" {
+		fields = append(fields, F("request_id", id))
+	}
+	c.log().Debug("response", append(fields, F("body", body))...)
+}
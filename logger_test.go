@@ -0,0 +1,96 @@
+package iconik
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingLogger keeps every message it receives, rendered as text.
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *recordingLogger) record(level Level, msg string, fields []Field) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	line := level.String() + " " + msg
+	for _, f := range fields {
+		line += fmt.Sprintf(" %s=%v", f.Key, f.Value)
+	}
+	r.lines = append(r.lines, line)
+}
+
+func (r *recordingLogger) Debug(msg string, fields ...Field) { r.record(LevelDebug, msg, fields) }
+func (r *recordingLogger) Info(msg string, fields ...Field)  { r.record(LevelInfo, msg, fields) }
+func (r *recordingLogger) Warn(msg string, fields ...Field)  { r.record(LevelWarn, msg, fields) }
+func (r *recordingLogger) Error(msg string, fields ...Field) { r.record(LevelError, msg, fields) }
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{
+			`{"id":"f1","upload_credentials":{"authorizationToken":"secret"},"upload_filename":"a.mov"}`,
+			`{"id":"f1","upload_credentials":"[REDACTED]","upload_filename":"a.mov"}`,
+		},
+		{
+			`{"authorization_token": "secret", "upload_url": "https://pod.backblaze.com/b2api/v2/b2_upload_part/abc"}`,
+			`{"authorization_token":"[REDACTED]", "upload_url": "https://pod.backblaze.com/b2api/v2/b2_upload_part/abc"}`,
+		},
+		{
+			`{"objects":[{"url":"https://storage.test/file.mp4?Expires=1&Signature=abc"}]}`,
+			`{"objects":[{"url":"https://storage.test/file.mp4?[REDACTED]"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		if got := redact(tt.in); got != tt.want {
+			t.Errorf("redact(%s) got %s; wanted %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(log.New(&buf, "", 0), LevelInfo)
+	l.Debug("hidden")
+	l.Info("request", F("method", "GET"), F("status", 200), F("endpoint", "a b"))
+	if got, want := buf.String(), "INFO request method=GET status=200 endpoint=\"a b\"\n"; got != want {
+		t.Errorf("StdLogger wrote %q; wanted %q", got, want)
+	}
+}
+
+func TestIClient_LoggerRedactsSecrets(t *testing.T) {
+	signed := "https://test.com/url?Signature=topsecret"
+	logger := &recordingLogger{}
+	_, client := newTestServer(t, func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-Request-Id", "req-123")
+		payload, _ := json.Marshal(GetResponse{Objects: []Object{{URL: signed}}})
+		rw.Write(payload)
+	}, WithLogger(logger))
+	url, err := client.GenerateSignedProxyUrl("testAssetId")
+	if err != nil {
+		t.Fatalf("GenerateSignedProxyUrl() got %v; wanted no error", err)
+	}
+	if url != signed {
+		t.Errorf("GenerateSignedProxyUrl() got %s; wanted the unredacted %s", url, signed)
+	}
+
+	all := strings.Join(logger.lines, "\n")
+	for _, secret := range []string{"testAppID", "testToken", "topsecret"} {
+		if strings.Contains(all, secret) {
+			t.Errorf("log output contains %q:\n%s", secret, all)
+		}
+	}
+	for _, want := range []string{"status=200", "request_id=req-123", "method=GET"} {
+		if !strings.Contains(all, want) {
+			t.Errorf("log output is missing %q:\n%s", want, all)
+		}
+	}
+}
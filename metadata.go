@@ -0,0 +1,268 @@
+package iconik
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	metadataViewsEndpoint             = "metadata/v1/views/"
+	metadataViewEndpointTemplate      = "metadata/v1/views/%s/"
+	metadataFieldsEndpoint            = "metadata/v1/fields/"
+	assetMetadataViewEndpointTemplate = "metadata/v1/assets/%s/views/%s/"
+)
+
+// Metadata field types.
+const (
+	FieldTypeString   = "string"
+	FieldTypeText     = "text"
+	FieldTypeInteger  = "integer"
+	FieldTypeFloat    = "float"
+	FieldTypeBoolean  = "boolean"
+	FieldTypeDate     = "date"
+	FieldTypeDateTime = "datetime"
+	FieldTypeDropDown = "drop_down"
+	FieldTypeTagCloud = "tag_cloud"
+	FieldTypeEmail    = "email"
+	FieldTypeURL      = "url"
+)
+
+const metadataDateLayout = "2006-01-02"
+
+// ErrInvalidMetadata is matched by errors.Is when UpdateAssetMetadata
+// rejects a value that does not fit its field definition.
+var ErrInvalidMetadata = errors.New("iconik: invalid metadata value")
+
+// MetadataValues maps field names to their values. Every field holds a
+// slice, even single-value fields. Values have the Go type matching the field
+// type: string for text-like fields, int64 for integer, float64 for float,
+// bool for boolean and time.Time for date and datetime fields. Values of
+// unknown field types are left as decoded from JSON.
+type MetadataValues map[string][]interface{}
+
+// ListMetadataViews returns every metadata view.
+func (c *IClient) ListMetadataViews(ctx context.Context) ([]MetadataView, error) {
+	c.log().Debug("ListMetadataViews", F("endpoint", metadataViewsEndpoint))
+	return listAll[MetadataView](ctx, c, apiCall{method: http.MethodGet, path: metadataViewsEndpoint})
+}
+
+// GetMetadataView returns a metadata view with its field definitions.
+func (c *IClient) GetMetadataView(ctx context.Context, viewID string) (*MetadataView, error) {
+	endpoint := fmt.Sprintf(metadataViewEndpointTemplate, url.PathEscape(viewID))
+	c.log().Debug("GetMetadataView", F("endpoint", endpoint))
+	return doJSON[MetadataView](ctx, c, apiCall{method: http.MethodGet, path: endpoint})
+}
+
+// ListMetadataFields returns the definitions of every metadata field.
+func (c *IClient) ListMetadataFields(ctx context.Context) ([]MetadataField, error) {
+	c.log().Debug("ListMetadataFields", F("endpoint", metadataFieldsEndpoint))
+	return listAll[MetadataField](ctx, c, apiCall{method: http.MethodGet, path: metadataFieldsEndpoint})
+}
+
+// GetAssetMetadata returns an asset's values for the fields of a metadata
+// view, converted according to the view's field definitions.
+func (c *IClient) GetAssetMetadata(ctx context.Context, assetID, viewID string) (MetadataValues, error) {
+	view, err := c.GetMetadataView(ctx, viewID)
+	if err != nil {
+		return nil, err
+	}
+	return c.assetMetadata(ctx, assetID, view)
+}
+
+func (c *IClient) assetMetadata(ctx context.Context, assetID string, view *MetadataView) (MetadataValues, error) {
+	endpoint := fmt.Sprintf(assetMetadataViewEndpointTemplate, url.PathEscape(assetID), url.PathEscape(view.Id))
+	c.log().Debug("GetAssetMetadata", F("endpoint", endpoint))
+	resp, err := doJSON[metadataValuesSchema](ctx, c, apiCall{method: http.MethodGet, path: endpoint})
+	if err != nil {
+		return nil, err
+	}
+
+	fields := viewFields(view)
+	md := make(MetadataValues, len(resp.MetadataValues))
+	for name, values := range resp.MetadataValues {
+		field, ok := fields[name]
+		out := make([]interface{}, 0, len(values.FieldValues))
+		for _, v := range values.FieldValues {
+			if ok {
+				if converted, err := decodeMetadataValue(field, v.Value); err == nil {
+					out = append(out, converted)
+					continue
+				}
+			}
+			out = append(out, v.Value)
+		}
+		md[name] = out
+	}
+	return md, nil
+}
+
+// UpdateAssetMetadata sets an asset's values for fields of a metadata view.
+// Fields not in values are left unchanged; an empty slice clears a field.
+// Every value is checked against the view's field definitions first, and
+// nothing is sent if any of them is invalid.
+func (c *IClient) UpdateAssetMetadata(ctx context.Context, assetID, viewID string, values MetadataValues) error {
+	view, err := c.GetMetadataView(ctx, viewID)
+	if err != nil {
+		return err
+	}
+	return c.updateAssetMetadata(ctx, assetID, view, values)
+}
+
+func (c *IClient) updateAssetMetadata(ctx context.Context, assetID string, view *MetadataView, values MetadataValues) error {
+	fields := viewFields(view)
+	body := metadataValuesSchema{MetadataValues: make(map[string]metadataFieldValues, len(values))}
+	for name, vs := range values {
+		field, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("%w: field %s is not in view %s", ErrInvalidMetadata, name, view.Name)
+		}
+		if field.ReadOnly {
+			return fmt.Errorf("%w: field %s is read-only", ErrInvalidMetadata, name)
+		}
+		if len(vs) > 1 && !field.Multi {
+			return fmt.Errorf("%w: field %s takes a single value, got %d", ErrInvalidMetadata, name, len(vs))
+		}
+		if len(vs) == 0 && field.Required {
+			return fmt.Errorf("%w: field %s is required", ErrInvalidMetadata, name)
+		}
+		encoded := make([]metadataFieldValue, len(vs))
+		for i, v := range vs {
+			e, err := encodeMetadataValue(field, v)
+			if err != nil {
+				return fmt.Errorf("%w: field %s: %v", ErrInvalidMetadata, name, err)
+			}
+			encoded[i] = metadataFieldValue{Value: e}
+		}
+		body.MetadataValues[name] = metadataFieldValues{FieldValues: encoded}
+	}
+
+	endpoint := fmt.Sprintf(assetMetadataViewEndpointTemplate, url.PathEscape(assetID), url.PathEscape(view.Id))
+	c.log().Debug("UpdateAssetMetadata", F("endpoint", endpoint))
+	_, err := c.send(ctx, apiCall{method: http.MethodPut, path: endpoint, body: body})
+	return err
+}
+
+func viewFields(view *MetadataView) map[string]MetadataField {
+	fields := make(map[string]MetadataField, len(view.ViewFields))
+	for _, f := range view.ViewFields {
+		fields[f.Name] = f
+	}
+	return fields
+}
+
+// decodeMetadataValue converts a value decoded from JSON to the Go type of
+// field.
+func decodeMetadataValue(field MetadataField, v interface{}) (interface{}, error) {
+	switch field.FieldType {
+	case FieldTypeInteger:
+		switch n := v.(type) {
+		case float64:
+			return int64(n), nil
+		case string:
+			return strconv.ParseInt(n, 10, 64)
+		}
+	case FieldTypeFloat:
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case string:
+			return strconv.ParseFloat(n, 64)
+		}
+	case FieldTypeBoolean:
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case string:
+			return strconv.ParseBool(b)
+		}
+	case FieldTypeDate, FieldTypeDateTime:
+		if s, ok := v.(string); ok {
+			return parseMetadataTime(s)
+		}
+	default:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %T for %s field", v, field.FieldType)
+}
+
+// encodeMetadataValue checks that v suits field and converts it to its
+// JSON representation.
+func encodeMetadataValue(field MetadataField, v interface{}) (interface{}, error) {
+	switch field.FieldType {
+	case FieldTypeInteger:
+		switch n := v.(type) {
+		case int:
+			return n, nil
+		case int32:
+			return n, nil
+		case int64:
+			return n, nil
+		}
+	case FieldTypeFloat:
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case float32:
+			return n, nil
+		case int:
+			return n, nil
+		case int64:
+			return n, nil
+		}
+	case FieldTypeBoolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case FieldTypeDate, FieldTypeDateTime:
+		var t time.Time
+		switch d := v.(type) {
+		case time.Time:
+			t = d
+		case string:
+			parsed, err := parseMetadataTime(d)
+			if err != nil {
+				return nil, err
+			}
+			t = parsed
+		default:
+			return nil, fmt.Errorf("got %T, wanted time.Time", v)
+		}
+		if field.FieldType == FieldTypeDate {
+			return t.Format(metadataDateLayout), nil
+		}
+		return t.UTC().Format(time.RFC3339), nil
+	default:
+		s, ok := v.(string)
+		if !ok {
+			break
+		}
+		if field.FieldType == FieldTypeDropDown && len(field.Options) > 0 && !hasOption(field, s) {
+			return nil, fmt.Errorf("%q is not one of the field's options", s)
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("got %T for %s field", v, field.FieldType)
+}
+
+func hasOption(field MetadataField, value string) bool {
+	for _, o := range field.Options {
+		if o.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func parseMetadataTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(metadataDateLayout, s)
+}
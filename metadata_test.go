@@ -0,0 +1,156 @@
+package iconik
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testMetadataView = `{"id":"v1","name":"Course","view_fields":[
+	{"name":"_gcvi_tags","field_type":"tag_cloud","multi":true},
+	{"name":"room","field_type":"drop_down","options":[{"label":"A","value":"a"},{"label":"B","value":"b"}]},
+	{"name":"seats","field_type":"integer"},
+	{"name":"rating","field_type":"float"},
+	{"name":"published","field_type":"boolean"},
+	{"name":"held_on","field_type":"date"},
+	{"name":"recorded_at","field_type":"datetime"},
+	{"name":"source","field_type":"string","read_only":true}]}`
+
+func metadataServer(t *testing.T, put *map[string]interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/metadata/v1/views/v1/":
+			w.Write([]byte(testMetadataView))
+		case r.URL.Path == "/metadata/v1/assets/a1/views/v1/" && r.Method == http.MethodGet:
+			w.Write([]byte(`{"metadata_values":{
+				"_gcvi_tags":{"field_values":[{"value":"Teaching"},{"value":"Lab"}]},
+				"seats":{"field_values":[{"value":30}]},
+				"rating":{"field_values":[{"value":4.5}]},
+				"published":{"field_values":[{"value":true}]},
+				"held_on":{"field_values":[{"value":"2022-03-04"}]},
+				"recorded_at":{"field_values":[{"value":"2022-03-04T10:00:00+00:00"}]}}}`))
+		case r.URL.Path == "/metadata/v1/assets/a1/views/v1/" && r.Method == http.MethodPut:
+			json.NewDecoder(r.Body).Decode(put)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestIClient_GetAssetMetadata(t *testing.T) {
+	_, client := newTestServer(t, metadataServer(t, nil))
+
+	md, err := client.GetAssetMetadata(context.Background(), "a1", "v1")
+	if err != nil {
+		t.Fatalf("GetAssetMetadata() got error %v", err)
+	}
+	want := MetadataValues{
+		"_gcvi_tags":  {"Teaching", "Lab"},
+		"seats":       {int64(30)},
+		"rating":      {4.5},
+		"published":   {true},
+		"held_on":     {time.Date(2022, 3, 4, 0, 0, 0, 0, time.UTC)},
+		"recorded_at": {time.Date(2022, 3, 4, 10, 0, 0, 0, time.FixedZone("", 0))},
+	}
+	for name, values := range want {
+		if len(md[name]) != len(values) {
+			t.Errorf("GetAssetMetadata()[%s] got %v; wanted %v", name, md[name], values)
+			continue
+		}
+		for i, v := range values {
+			got := md[name][i]
+			if tv, ok := v.(time.Time); ok {
+				if gt, ok := got.(time.Time); !ok || !gt.Equal(tv) {
+					t.Errorf("GetAssetMetadata()[%s][%d] got %v; wanted %v", name, i, got, v)
+				}
+			} else if got != v {
+				t.Errorf("GetAssetMetadata()[%s][%d] got %#v; wanted %#v", name, i, got, v)
+			}
+		}
+	}
+}
+
+func TestIClient_UpdateAssetMetadata(t *testing.T) {
+	var put map[string]interface{}
+	_, client := newTestServer(t, metadataServer(t, &put))
+
+	err := client.UpdateAssetMetadata(context.Background(), "a1", "v1", MetadataValues{
+		"_gcvi_tags":  {"Teaching", "Lab"},
+		"room":        {"b"},
+		"seats":       {25},
+		"published":   {false},
+		"held_on":     {time.Date(2022, 3, 4, 15, 0, 0, 0, time.UTC)},
+		"recorded_at": {"2022-03-04T10:00:00Z"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateAssetMetadata() got error %v", err)
+	}
+	values := func(vs ...interface{}) interface{} {
+		out := []interface{}{}
+		for _, v := range vs {
+			out = append(out, map[string]interface{}{"value": v})
+		}
+		return map[string]interface{}{"field_values": out}
+	}
+	want := map[string]interface{}{"metadata_values": map[string]interface{}{
+		"_gcvi_tags":  values("Teaching", "Lab"),
+		"room":        values("b"),
+		"seats":       values(25.0),
+		"published":   values(false),
+		"held_on":     values("2022-03-04"),
+		"recorded_at": values("2022-03-04T10:00:00Z"),
+	}}
+	if !reflect.DeepEqual(put, want) {
+		t.Errorf("UpdateAssetMetadata() sent %v; wanted %v", put, want)
+	}
+}
+
+func TestIClient_UpdateAssetMetadataValidation(t *testing.T) {
+	var put map[string]interface{}
+	_, client := newTestServer(t, metadataServer(t, &put))
+
+	tests := map[string]MetadataValues{
+		"unknown field":      {"nope": {"x"}},
+		"read-only field":    {"source": {"x"}},
+		"multiple values":    {"room": {"a", "b"}},
+		"not an option":      {"room": {"c"}},
+		"wrong integer type": {"seats": {"thirty"}},
+		"wrong boolean type": {"published": {"yes"}},
+		"unparseable date":   {"held_on": {"March 4th"}},
+		"wrong float type":   {"rating": {"high"}},
+		"non-string tag":     {"_gcvi_tags": {42}},
+	}
+	for name, md := range tests {
+		err := client.UpdateAssetMetadata(context.Background(), "a1", "v1", md)
+		if !errors.Is(err, ErrInvalidMetadata) {
+			t.Errorf("UpdateAssetMetadata() with %s got %v; wanted ErrInvalidMetadata", name, err)
+		}
+	}
+	if put != nil {
+		t.Errorf("UpdateAssetMetadata() sent %v for invalid values; wanted nothing sent", put)
+	}
+}
+
+func TestIClient_ListMetadataViews(t *testing.T) {
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Write([]byte(`{"objects":[{"id":"v1","name":"Course"}],"page":1,"pages":2}`))
+		case "2":
+			w.Write([]byte(`{"objects":[{"id":"v2","name":"Rights"}],"page":2,"pages":2}`))
+		}
+	})
+
+	views, err := client.ListMetadataViews(context.Background())
+	if err != nil {
+		t.Fatalf("ListMetadataViews() got error %v", err)
+	}
+	if len(views) != 2 || views[0].Id != "v1" || views[1].Id != "v2" {
+		t.Errorf("ListMetadataViews() got %+v; wanted v1 and v2", views)
+	}
+}
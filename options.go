@@ -0,0 +1,207 @@
+package iconik
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout bounds every API request made by a client that was not
+	// given its own http.Client or timeout.
+	DefaultTimeout = 2 * time.Minute
+
+	// DefaultUserAgent is sent with every API request unless overridden
+	// with WithUserAgent.
+	DefaultUserAgent = "iconikclient2"
+)
+
+// Option configures an IClient created by NewIClientWithOptions.
+type Option func(*options) error
+
+// options collects the settings that have to be combined before the
+// http.Client can be built.
+type options struct {
+	client     *IClient
+	httpClient *http.Client
+	transport  http.RoundTripper
+	timeout    *time.Duration
+	proxy      func(*http.Request) (*url.URL, error)
+	tlsConfig  *tls.Config
+}
+
+// NewIClientWithOptions creates a new Client for accessing the Iconik API,
+// configured by opts. An empty host uses IconikHost.
+func NewIClientWithOptions(creds Credentials, host string, opts ...Option) (*IClient, error) {
+	if host == "" {
+		host = IconikHost
+	} else if !strings.HasSuffix(host, "/") {
+		host = host + "/"
+	}
+	o := &options{
+		client: &IClient{
+			Credentials: creds,
+			host:        host,
+			userAgent:   DefaultUserAgent,
+		},
+	}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	httpClient, err := o.buildHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	o.client.httpClient = httpClient
+	if a, ok := o.client.Authenticator.(clientUser); ok {
+		a.useClient(httpClient, o.client.host)
+	}
+	return o.client, nil
+}
+
+func (o *options) buildHTTPClient() (*http.Client, error) {
+	hc := &http.Client{Timeout: DefaultTimeout}
+	if o.httpClient != nil {
+		// Copy so that later options don't modify the caller's client.
+		copied := *o.httpClient
+		hc = &copied
+	}
+	if o.transport != nil {
+		hc.Transport = o.transport
+	}
+	if o.timeout != nil {
+		hc.Timeout = *o.timeout
+	}
+	if o.proxy == nil && o.tlsConfig == nil {
+		return hc, nil
+	}
+
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t, ok := base.(*http.Transport)
+	if !ok {
+		return nil, errors.New("WithProxy and WithTLSConfig require the transport to be an *http.Transport")
+	}
+	t = t.Clone()
+	if o.proxy != nil {
+		t.Proxy = o.proxy
+	}
+	if o.tlsConfig != nil {
+		t.TLSClientConfig = o.tlsConfig
+	}
+	hc.Transport = t
+	return hc, nil
+}
+
+// WithHTTPClient makes the client send requests through a copy of hc.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(o *options) error {
+		if hc == nil {
+			return errors.New("WithHTTPClient: nil *http.Client")
+		}
+		o.httpClient = hc
+		return nil
+	}
+}
+
+// WithTransport sets the RoundTripper used to send requests, e.g. a tracing
+// transport or a test double.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *options) error {
+		o.transport = rt
+		return nil
+	}
+}
+
+// WithTimeout bounds the total time of each HTTP attempt. Zero means no
+// timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		o.timeout = &d
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every API request.
+func WithUserAgent(ua string) Option {
+	return func(o *options) error {
+		o.client.userAgent = ua
+		return nil
+	}
+}
+
+// WithProxy sets the proxy function of the transport, e.g.
+// http.ProxyURL(corporateProxy).
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(o *options) error {
+		o.proxy = proxy
+		return nil
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the transport.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *options) error {
+		o.tlsConfig = cfg
+		return nil
+	}
+}
+
+// WithLogger sends the client's diagnostic output to l. Sensitive values
+// are redacted before they reach it. Use NewStdLogger to log to a standard
+// library *log.Logger.
+func WithLogger(l Logger) Option {
+	return func(o *options) error {
+		o.client.logger = l
+		return nil
+	}
+}
+
+// WithAuthenticator authenticates requests with a instead of the static
+// Credentials passed to NewIClientWithOptions.
+func WithAuthenticator(a Authenticator) Option {
+	return func(o *options) error {
+		o.client.Authenticator = a
+		return nil
+	}
+}
+
+// WithDebug enables debugging output about API calls.
+func WithDebug(debug bool) Option {
+	return func(o *options) error {
+		o.client.Debug = debug
+		return nil
+	}
+}
+
+// WithRetryPolicy sets the policy used to retry transient failures.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(o *options) error {
+		o.client.RetryPolicy = &p
+		return nil
+	}
+}
+
+// WithRateLimits throttles requests per endpoint family.
+func WithRateLimits(r *RateLimits) Option {
+	return func(o *options) error {
+		o.client.RateLimits = r
+		return nil
+	}
+}
+
+// WithCollectionCache shares cache between calls that resolve collection
+// paths, such as GetCollectionIDs.
+func WithCollectionCache(cache *CollectionCache) Option {
+	return func(o *options) error {
+		o.client.CollectionCache = cache
+		return nil
+	}
+}
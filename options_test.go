@@ -0,0 +1,88 @@
+package iconik
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// roundTripFunc lets a function act as an http.RoundTripper test double.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func jsonResponse(status int, v interface{}) *http.Response {
+	payload, _ := json.Marshal(v)
+	return &http.Response{
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewReader(payload)),
+	}
+}
+
+func TestNewIClientWithOptions_Transport(t *testing.T) {
+	expected := "https://test.com/url"
+	var gotUA, gotHost string
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		gotUA = req.Header.Get("User-Agent")
+		gotHost = req.URL.Host
+		return jsonResponse(http.StatusOK, GetResponse{Objects: []Object{{URL: expected}}}), nil
+	})
+
+	client, err := NewIClientWithOptions(Credentials{AppID: "testAppID", Token: "testToken"}, "https://iconik.test/API",
+		WithTransport(rt), WithUserAgent("test-agent"))
+	if err != nil {
+		t.Fatalf("NewIClientWithOptions() got %v; wanted no error", err)
+	}
+	url, err := client.GenerateSignedProxyUrl("testAssetId")
+	if err != nil {
+		t.Fatalf("GenerateSignedProxyUrl() got %v; wanted no error", err)
+	}
+	if url != expected {
+		t.Errorf("GenerateSignedProxyUrl() got %s; wanted %s", url, expected)
+	}
+	if gotUA != "test-agent" {
+		t.Errorf("User-Agent got %q; wanted %q", gotUA, "test-agent")
+	}
+	if gotHost != "iconik.test" {
+		t.Errorf("request host got %q; wanted %q", gotHost, "iconik.test")
+	}
+}
+
+func TestNewIClientWithOptions_HTTPClient(t *testing.T) {
+	hc := &http.Client{Timeout: time.Second}
+	proxyURL, _ := url.Parse("http://proxy.test:3128")
+	client, err := NewIClientWithOptions(Credentials{}, "", WithHTTPClient(hc),
+		WithTimeout(5*time.Second), WithProxy(http.ProxyURL(proxyURL)))
+	if err != nil {
+		t.Fatalf("NewIClientWithOptions() got %v; wanted no error", err)
+	}
+	if hc.Timeout != time.Second || hc.Transport != nil {
+		t.Errorf("caller's http.Client was modified: %+v", hc)
+	}
+	if client.httpClient.Timeout != 5*time.Second {
+		t.Errorf("client timeout got %v; wanted %v", client.httpClient.Timeout, 5*time.Second)
+	}
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("client transport got %T; wanted *http.Transport", client.httpClient.Transport)
+	}
+	got, _ := transport.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "app.iconik.io"}})
+	if got.String() != proxyURL.String() {
+		t.Errorf("proxy got %v; wanted %v", got, proxyURL)
+	}
+}
+
+func TestNewIClientWithOptions_ProxyNeedsHTTPTransport(t *testing.T) {
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) { return nil, nil })
+	_, err := NewIClientWithOptions(Credentials{}, "", WithTransport(rt), WithProxy(http.ProxyFromEnvironment))
+	if err == nil {
+		t.Errorf("NewIClientWithOptions(WithTransport, WithProxy) got no error; wanted one")
+	}
+}
@@ -0,0 +1,158 @@
+package iconik
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultProgressInterval is how often an upload reports its progress by
+// default.
+const DefaultProgressInterval = 5 * time.Second
+
+// UploadProgress describes how far an upload has got.
+type UploadProgress struct {
+	// BytesSent is how much of the file has been sent to storage,
+	// including parts sent before the upload was resumed.
+	BytesSent  int64
+	TotalBytes int64
+	// Part is the number of the part that has just been sent, or 0 in a
+	// periodic report.
+	Part  int
+	Parts int
+	// Throughput is the average rate in bytes per second since the upload,
+	// or its resumption, started.
+	Throughput float64
+	// ETA estimates the time left; it is zero until some bytes are sent.
+	ETA time.Duration
+}
+
+// progressTracker counts the bytes of an upload as they are sent, reports
+// them to the caller's callback and keeps the upload's job up to date.
+type progressTracker struct {
+	c     *IClient
+	jobID string
+	total int64
+	parts int
+	base  int64 // bytes sent before a resume
+	start time.Time
+	sent  int64 // updated atomically
+	fn    func(UploadProgress)
+
+	mu sync.Mutex // serializes calls to fn
+
+	jobMu       sync.Mutex // guards lastPercent; not held while sending
+	lastPercent int
+}
+
+func newProgressTracker(c *IClient, jobID string, total, base int64, parts int, fn func(UploadProgress)) *progressTracker {
+	return &progressTracker{c: c, jobID: jobID, total: total, parts: parts, base: base, start: time.Now(), fn: fn}
+}
+
+// countingReader counts the bytes read through it towards a
+// progressTracker.
+type countingReader struct {
+	r io.Reader
+	p *progressTracker
+}
+
+func (r countingReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	atomic.AddInt64(&r.p.sent, int64(n))
+	return n, err
+}
+
+// counted returns a reader that counts what is read from r as sent.
+func (p *progressTracker) counted(r io.Reader) io.Reader {
+	return countingReader{r: r, p: p}
+}
+
+func (p *progressTracker) snapshot(part int) UploadProgress {
+	sent := atomic.LoadInt64(&p.sent)
+	progress := UploadProgress{
+		BytesSent:  p.base + sent,
+		TotalBytes: p.total,
+		Part:       part,
+		Parts:      p.parts,
+	}
+	if elapsed := time.Since(p.start).Seconds(); elapsed > 0 && sent > 0 {
+		progress.Throughput = float64(sent) / elapsed
+		left := p.total - progress.BytesSent
+		if left < 0 {
+			left = 0
+		}
+		progress.ETA = time.Duration(float64(left) / progress.Throughput * float64(time.Second))
+	}
+	return progress
+}
+
+// report passes the current progress to the callback, if any. part is the
+// part that has just been sent, or 0.
+func (p *progressTracker) report(part int) {
+	if p.fn == nil {
+		return
+	}
+	progress := p.snapshot(part)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fn(progress)
+}
+
+// updateJob sets the job's progress_processed to the percentage sent, if it
+// has changed. It stays below 100 until FinishUpload finishes the job.
+// Failures are only logged, since they do not affect the upload.
+func (p *progressTracker) updateJob(ctx context.Context) {
+	if p.jobID == "" || p.total == 0 {
+		return
+	}
+	percent := int(p.snapshot(0).BytesSent * 100 / p.total)
+	if percent > 99 {
+		percent = 99
+	}
+	p.jobMu.Lock()
+	unchanged := percent == p.lastPercent
+	p.jobMu.Unlock()
+	if unchanged {
+		return
+	}
+	endpoint := fmt.Sprintf(patchJobCompleteEndpointTemplate, p.jobID)
+	body := map[string]int{"progress_processed": percent}
+	if _, err := p.c.send(ctx, apiCall{method: http.MethodPatch, path: endpoint, body: body}); err != nil {
+		p.c.log().Warn("updating job progress", F("job_id", p.jobID), F("error", err))
+		return
+	}
+	p.jobMu.Lock()
+	p.lastPercent = percent
+	p.jobMu.Unlock()
+}
+
+// run reports progress every interval until stop is called.
+func (p *progressTracker) run(ctx context.Context, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.report(0)
+				p.updateJob(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
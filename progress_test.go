@@ -0,0 +1,137 @@
+package iconik
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIClient_UploadFileProgress(t *testing.T) {
+	s := newFakeUploadServer(t)
+	client := newTestClient(t, s.URL)
+
+	size := int64(2*MinPartSize + 100)
+	var reports []UploadProgress
+	_, err := client.UploadFile(context.Background(), bytes.NewReader(make([]byte, size)), size, UploadOptions{
+		CollectionID: "c",
+		FileName:     "f",
+		PartSize:     MinPartSize,
+		Concurrency:  1,
+		Progress:     func(p UploadProgress) { reports = append(reports, p) },
+	})
+	if err != nil {
+		t.Fatalf("UploadFile() got error %v", err)
+	}
+
+	var parts []UploadProgress
+	for _, p := range reports {
+		if p.Part != 0 {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) != 3 {
+		t.Fatalf("UploadFile() reported %d parts; wanted 3", len(parts))
+	}
+	for i, p := range parts {
+		wantSent := int64(i+1) * MinPartSize
+		if wantSent > size {
+			wantSent = size
+		}
+		if p.Part != i+1 || p.Parts != 3 || p.BytesSent != wantSent || p.TotalBytes != size {
+			t.Errorf("report %d got %+v; wanted part %d of 3 with %d of %d bytes sent", i, p, i+1, wantSent, size)
+		}
+		if p.Throughput <= 0 {
+			t.Errorf("report %d got throughput %v; wanted it positive", i, p.Throughput)
+		}
+	}
+	if last := parts[2]; last.ETA != 0 {
+		t.Errorf("last report got ETA %v; wanted 0", last.ETA)
+	}
+}
+
+func TestIClient_UploadFileCancel(t *testing.T) {
+	s := newFakeUploadServer(t)
+	client := newTestClient(t, s.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	size := int64(2*MinPartSize + 100)
+	_, err := client.UploadFile(ctx, bytes.NewReader(make([]byte, size)), size, UploadOptions{
+		CollectionID: "c",
+		FileName:     "f",
+		PartSize:     MinPartSize,
+		Concurrency:  1,
+		Progress: func(p UploadProgress) {
+			if p.Part == 1 {
+				cancel()
+			}
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("UploadFile() got %v; wanted context.Canceled", err)
+	}
+	if len(s.parts) != 1 {
+		t.Errorf("UploadFile() sent %d parts; wanted to stop after 1", len(s.parts))
+	}
+	if s.called("finish") {
+		t.Errorf("UploadFile() finished a cancelled upload")
+	}
+	if !s.abandoned("ABORTED") {
+		t.Errorf("UploadFile() got calls %v; wanted the asset deleted and the job aborted", s.calls)
+	}
+}
+
+func TestProgressTracker_UpdateJob(t *testing.T) {
+	s := newFakeUploadServer(t)
+	client := newTestClient(t, s.URL)
+
+	p := newProgressTracker(client, "job-1", 1000, 100, 1, nil)
+	var percents []int
+	for _, sent := range []int64{150, 150, 400, 900} {
+		p.sent = sent
+		p.updateJob(context.Background())
+	}
+	for i, c := range s.calls {
+		if c != "PATCH /jobs/v1/jobs/job-1" {
+			t.Errorf("updateJob() called %s; wanted the job", c)
+			continue
+		}
+		var body struct {
+			ProgressProcessed int `json:"progress_processed"`
+		}
+		json.Unmarshal(s.bodies[i], &body)
+		percents = append(percents, body.ProgressProcessed)
+	}
+	want := []int{25, 50, 99}
+	if len(percents) != len(want) || percents[0] != want[0] || percents[1] != want[1] || percents[2] != want[2] {
+		t.Errorf("updateJob() set progress %v; wanted %v", percents, want)
+	}
+}
+
+func TestProgressTracker_ReportDoesNotWaitForJobUpdate(t *testing.T) {
+	patching, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		close(patching)
+		<-release
+	})
+
+	p := newProgressTracker(client, "job-1", 1000, 0, 2, func(UploadProgress) {})
+	p.sent = 500
+	go p.updateJob(context.Background())
+	<-patching
+	reported := make(chan struct{})
+	go func() {
+		p.report(1)
+		close(reported)
+	}()
+	select {
+	case <-reported:
+	case <-time.After(5 * time.Second):
+		t.Errorf("report() blocked while the job update was in flight")
+	}
+}
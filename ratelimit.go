@@ -0,0 +1,164 @@
+package iconik
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointFamily groups Iconik API endpoints that share a request quota.
+type EndpointFamily string
+
+const (
+	FamilySearch EndpointFamily = "search"
+	FamilyFiles  EndpointFamily = "files"
+	FamilyAssets EndpointFamily = "assets"
+	FamilyJobs   EndpointFamily = "jobs"
+	FamilyOther  EndpointFamily = "other"
+)
+
+// endpointFamily returns the family of an API path relative to the host,
+// e.g. "files/v1/assets/..." belongs to FamilyFiles.
+func endpointFamily(apiPath string) EndpointFamily {
+	apiPath = strings.TrimPrefix(apiPath, "/")
+	if i := strings.IndexByte(apiPath, '/'); i >= 0 {
+		apiPath = apiPath[:i]
+	}
+	switch f := EndpointFamily(apiPath); f {
+	case FamilySearch, FamilyFiles, FamilyAssets, FamilyJobs:
+		return f
+	}
+	return FamilyOther
+}
+
+// requestFamily returns the endpoint family of a request built by newRequest.
+func (c *IClient) requestFamily(u *url.URL) EndpointFamily {
+	path := u.Path
+	if host, err := url.Parse(c.host); err == nil {
+		path = strings.TrimPrefix(path, host.Path)
+	}
+	return endpointFamily(path)
+}
+
+// RateLimiter blocks until the caller is allowed to send one request.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// TokenBucket is a RateLimiter that allows bursts of up to burst requests
+// and refills at rate requests per second. It is safe for concurrent use.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full TokenBucket. It returns an error if rate
+// is not positive.
+func NewTokenBucket(rate float64, burst int) (*TokenBucket, error) {
+	if !(rate > 0) {
+		return nil, fmt.Errorf("NewTokenBucket: rate must be positive, got %v", rate)
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}, nil
+}
+
+// Wait takes a token from the bucket, sleeping until one is available. If
+// ctx is done first, the reserved token is returned to the bucket.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if err := sleepContext(ctx, wait); err != nil {
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// RateLimitStats records how much time requests in one endpoint family
+// spent waiting on the rate limiter.
+type RateLimitStats struct {
+	Requests  int64         // requests that went through the limiter
+	Delayed   int64         // requests that had to wait
+	TotalWait time.Duration // sum of all waits
+	MaxWait   time.Duration // longest single wait
+}
+
+// RateLimits assigns a RateLimiter to each endpoint family. Families
+// without their own limiter use Default; if that is nil too, their requests
+// are not limited. It is safe for concurrent use once configured.
+type RateLimits struct {
+	Default  RateLimiter
+	Families map[EndpointFamily]RateLimiter
+
+	mu    sync.Mutex
+	stats map[EndpointFamily]RateLimitStats
+}
+
+// Wait blocks until a request in family may be sent.
+func (r *RateLimits) Wait(ctx context.Context, family EndpointFamily) error {
+	limiter, ok := r.Families[family]
+	if !ok {
+		limiter = r.Default
+	}
+	if limiter == nil {
+		return nil
+	}
+	start := time.Now()
+	err := limiter.Wait(ctx)
+	waited := time.Since(start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stats == nil {
+		r.stats = make(map[EndpointFamily]RateLimitStats)
+	}
+	s := r.stats[family]
+	s.Requests++
+	if waited > time.Millisecond {
+		s.Delayed++
+	}
+	s.TotalWait += waited
+	if waited > s.MaxWait {
+		s.MaxWait = waited
+	}
+	r.stats[family] = s
+	return err
+}
+
+// Stats returns a snapshot of the time spent waiting per endpoint family.
+func (r *RateLimits) Stats() map[EndpointFamily]RateLimitStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := make(map[EndpointFamily]RateLimitStats, len(r.stats))
+	for k, v := range r.stats {
+		stats[k] = v
+	}
+	return stats
+}
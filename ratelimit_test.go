@@ -0,0 +1,95 @@
+package iconik
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEndpointFamily(t *testing.T) {
+	tests := map[string]EndpointFamily{
+		searchEndpoint:               FamilySearch,
+		"/files/v1/assets/abc/files": FamilyFiles,
+		createCollectionEndpoint:     FamilyAssets,
+		jobStartEndpointTemplate:     FamilyJobs,
+		"auth/v1/auth/token/":        FamilyOther,
+	}
+	for path, want := range tests {
+		if got := endpointFamily(path); got != want {
+			t.Errorf("endpointFamily(%s) got %s; wanted %s", path, got, want)
+		}
+	}
+}
+
+func TestTokenBucket_Wait(t *testing.T) {
+	b := newTestBucket(t, 20, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := b.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() got %v; wanted no error", err)
+		}
+	}
+	// Two requests come out of the burst, the other two wait 50ms each.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("4 waits at 20/s with burst 2 took %v; wanted at least 100ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := newTestBucket(t, 1, 1).Wait(ctx); err != nil {
+		t.Errorf("Wait() with a token available got %v; wanted no error", err)
+	}
+	drained := newTestBucket(t, 0.001, 1)
+	drained.Wait(context.Background())
+	if err := drained.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait() on canceled context got %v; wanted %v", err, context.Canceled)
+	}
+}
+
+func TestNewTokenBucket_RejectsBadRate(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.NaN()} {
+		if b, err := NewTokenBucket(rate, 1); err == nil {
+			t.Errorf("NewTokenBucket(%v, 1) got %v; wanted an error", rate, b)
+		}
+	}
+}
+
+// newTestBucket is NewTokenBucket for rates known to be valid.
+func newTestBucket(t *testing.T, rate float64, burst int) *TokenBucket {
+	t.Helper()
+	b, err := NewTokenBucket(rate, burst)
+	if err != nil {
+		t.Fatalf("NewTokenBucket() got error %v", err)
+	}
+	return b
+}
+
+func TestIClient_RateLimitsPerFamily(t *testing.T) {
+	_, client := newTestServer(t, func(rw http.ResponseWriter, req *http.Request) {
+		payload, _ := json.Marshal(GetResponse{Objects: []Object{{URL: "https://test.com/url"}}})
+		rw.Write(payload)
+	})
+	client.RateLimits = &RateLimits{
+		Families: map[EndpointFamily]RateLimiter{FamilyFiles: newTestBucket(t, 20, 1)},
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := client.GenerateSignedProxyUrl("testAssetId"); err != nil {
+			t.Fatalf("GenerateSignedProxyUrl() got %v; wanted no error", err)
+		}
+	}
+	if _, err := client.SearchWithTag("tag", false); err != nil {
+		t.Fatalf("SearchWithTag() got %v; wanted no error", err)
+	}
+
+	stats := client.RateLimits.Stats()
+	files := stats[FamilyFiles]
+	if files.Requests != 3 || files.Delayed != 2 || files.TotalWait < 80*time.Millisecond {
+		t.Errorf("Stats()[files] got %+v; wanted 3 requests with 2 delayed by ~100ms in total", files)
+	}
+	if _, ok := stats[FamilySearch]; ok {
+		t.Errorf("Stats() has search entry %+v; wanted none for an unlimited family", stats[FamilySearch])
+	}
+}
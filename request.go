@@ -0,0 +1,143 @@
+package iconik
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// apiCall describes one request to the Iconik API.
+type apiCall struct {
+	method string
+	// path is relative to the host and may already carry a query string.
+	path  string
+	query url.Values
+	// body is encoded as JSON when non-nil.
+	body   interface{}
+	header http.Header
+	// accept lists the status codes that count as success. If empty, any
+	// 2xx status does.
+	accept []int
+}
+
+func (call apiCall) accepts(status int) bool {
+	if len(call.accept) == 0 {
+		return status >= 200 && status < 300
+	}
+	for _, s := range call.accept {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// endpoint returns the path of call with its query parameters.
+func (call apiCall) endpoint() string {
+	if len(call.query) == 0 {
+		return call.path
+	}
+	sep := "?"
+	if strings.Contains(call.path, "?") {
+		sep = "&"
+	}
+	return call.path + sep + call.query.Encode()
+}
+
+// send performs call and returns the response body. A status outside the
+// accepted set is returned as an *APIError.
+func (c *IClient) send(ctx context.Context, call apiCall) ([]byte, error) {
+	var reqBody io.Reader
+	if call.body != nil {
+		b, err := json.Marshal(call.body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := c.newRequest(ctx, call.method, call.endpoint(), reqBody, call.header)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.logResponse(resp, body)
+	if !call.accepts(resp.StatusCode) {
+		return nil, apiErrorFromResponse(resp, body)
+	}
+	return body, nil
+}
+
+// doJSON performs call and decodes the response body into a T. An empty
+// body decodes to the zero T.
+func doJSON[T any](ctx context.Context, c *IClient, call apiCall) (*T, error) {
+	body, err := c.send(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	result := new(T)
+	if len(bytes.TrimSpace(body)) == 0 {
+		return result, nil
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return nil, fmt.Errorf("decoding response of %s %s: %w", call.method, call.path, err)
+	}
+	return result, nil
+}
+
+// idResponse is the part of a created or fetched object most callers need.
+type idResponse struct {
+	Id string `json:"id"`
+}
+
+// doID performs call and returns the id of the object in the response.
+func (c *IClient) doID(ctx context.Context, call apiCall) (string, error) {
+	r, err := doJSON[idResponse](ctx, c, call)
+	if err != nil {
+		return "", err
+	}
+	return r.Id, nil
+}
+
+// listPage is the envelope of Iconik's paginated list responses.
+type listPage[T any] struct {
+	Objects []T `json:"objects"`
+	Page    int `json:"page"`
+	Pages   int `json:"pages"`
+}
+
+// listAll performs call for every page of a paginated list and returns the
+// objects of all pages.
+func listAll[T any](ctx context.Context, c *IClient, call apiCall) ([]T, error) {
+	var all []T
+	for page := 1; ; page++ {
+		query := url.Values{}
+		for k, v := range call.query {
+			query[k] = v
+		}
+		query.Set("page", strconv.Itoa(page))
+		call.query = query
+		resp, err := doJSON[listPage[T]](ctx, c, call)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, resp.Objects...)
+		if page >= resp.Pages || len(resp.Objects) == 0 {
+			return all, nil
+		}
+	}
+}
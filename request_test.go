@@ -0,0 +1,77 @@
+package iconik
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestApiCall_Endpoint(t *testing.T) {
+	tests := []struct {
+		call apiCall
+		want string
+	}{
+		{apiCall{path: searchEndpoint}, searchEndpoint},
+		{apiCall{path: searchEndpoint, query: url.Values{"page": {"2"}}}, searchEndpoint + "?page=2"},
+		{apiCall{path: postAssetEndpointTemplate, query: url.Values{"x": {"1"}}}, postAssetEndpointTemplate + "&x=1"},
+	}
+	for _, tt := range tests {
+		if got := tt.call.endpoint(); got != tt.want {
+			t.Errorf("endpoint() got %s; wanted %s", got, tt.want)
+		}
+	}
+}
+
+func TestDoJSON(t *testing.T) {
+	type echo struct {
+		Method string `json:"method"`
+		Query  string `json:"query"`
+		Title  string `json:"title"`
+	}
+	_, client := newTestServer(t, func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/echo":
+			var in map[string]string
+			json.NewDecoder(req.Body).Decode(&in)
+			rw.WriteHeader(http.StatusCreated)
+			json.NewEncoder(rw).Encode(echo{Method: req.Method, Query: req.URL.RawQuery, Title: in["title"]})
+		case "/empty":
+			rw.WriteHeader(http.StatusNoContent)
+		case "/garbage":
+			io.WriteString(rw, "<html>")
+		}
+	})
+	ctx := context.Background()
+
+	got, err := doJSON[echo](ctx, client, apiCall{
+		method: http.MethodPatch,
+		path:   "echo",
+		query:  url.Values{"a": {"b"}},
+		body:   map[string]string{"title": "t"},
+	})
+	if err != nil {
+		t.Fatalf("doJSON(echo) got %v; wanted no error", err)
+	}
+	if want := (echo{Method: http.MethodPatch, Query: "a=b", Title: "t"}); *got != want {
+		t.Errorf("doJSON(echo) got %+v; wanted %+v", *got, want)
+	}
+
+	_, err = doJSON[echo](ctx, client, apiCall{method: http.MethodGet, path: "echo", accept: []int{http.StatusOK}})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusCreated {
+		t.Errorf("doJSON(echo) accepting only 200 got %v; wanted *APIError with status 201", err)
+	}
+
+	if _, err := doJSON[echo](ctx, client, apiCall{method: http.MethodDelete, path: "empty"}); err != nil {
+		t.Errorf("doJSON(empty) got %v; wanted no error", err)
+	}
+
+	var syntaxErr *json.SyntaxError
+	if _, err := doJSON[echo](ctx, client, apiCall{method: http.MethodGet, path: "garbage"}); !errors.As(err, &syntaxErr) {
+		t.Errorf("doJSON(garbage) got %v; wanted a *json.SyntaxError", err)
+	}
+}
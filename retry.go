@@ -0,0 +1,249 @@
+package iconik
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how IClient retries requests that fail with a
+// transient error.
+//
+// Requests are only retried when it is safe to do so. Idempotent methods
+// (GET, PUT, PATCH, DELETE, ...) are retried on any retryable status code or
+// network error. POST requests may have been processed by Iconik even though
+// the response was lost, so unless RetryNonIdempotent is set they are only
+// retried when the server explicitly refused them (429 and 503) or when the
+// connection could not be established at all.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. Each subsequent
+	// retry multiplies the delay by Multiplier, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+
+	// Jitter is the fraction (0 to 1) of each delay that is randomized so
+	// that many clients backing off at once don't retry in lockstep.
+	Jitter float64
+
+	// RetryableStatus lists the HTTP status codes that are worth retrying.
+	RetryableStatus []int
+
+	// RetryNetworkErrors enables retrying requests that failed without
+	// receiving a response (connection resets, timeouts, ...).
+	RetryNetworkErrors bool
+
+	// RetryNonIdempotent allows POST requests to be retried under the same
+	// conditions as idempotent ones.
+	RetryNonIdempotent bool
+
+	// MaxRetryAfter caps how long a server-provided Retry-After header can
+	// make the client wait. A Retry-After longer than this stops retrying.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultRetryPolicy is used by an IClient that has no RetryPolicy set.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:        4,
+	InitialBackoff:     500 * time.Millisecond,
+	MaxBackoff:         30 * time.Second,
+	Multiplier:         2,
+	Jitter:             0.2,
+	RetryableStatus:    []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	RetryNetworkErrors: true,
+	MaxRetryAfter:      2 * time.Minute,
+}
+
+func (c *IClient) retryPolicy() RetryPolicy {
+	if c.NoRetry {
+		return RetryPolicy{MaxAttempts: 1}
+	}
+	if c.RetryPolicy != nil {
+		return *c.RetryPolicy
+	}
+	return DefaultRetryPolicy
+}
+
+// do sends req, retrying it according to the client's RetryPolicy. Every
+// attempt waits on the client's RateLimits first. If Iconik rejects the
+// credentials and the Authenticator can renew them, the request is
+// re-authenticated and sent once more without counting as a retry. The
+// request body is rewound between attempts, so requests whose body cannot be
+// replayed are only sent once.
+func (c *IClient) do(req *http.Request) (*http.Response, error) {
+	policy := c.retryPolicy()
+	reauthenticated := false
+	for attempt, sent := 1, false; ; sent = true {
+		if sent && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		if c.RateLimits != nil {
+			if err := c.RateLimits.Wait(req.Context(), c.requestFamily(req.URL)); err != nil {
+				return nil, err
+			}
+		}
+		start := time.Now()
+		resp, err := c.httpDoer().Do(req)
+		c.logAttempt(req, resp, err, attempt, time.Since(start))
+
+		canReplay := req.Body == nil || req.GetBody != nil
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && !reauthenticated && !c.NoRetry && canReplay {
+			if inv, ok := c.authenticator().(Invalidator); ok {
+				drainAndClose(resp)
+				c.log().Info("renewing credentials", F("method", req.Method), F("endpoint", req.URL.Path))
+				inv.Invalidate()
+				if err := c.authenticator().Authenticate(req.Context(), req); err != nil {
+					return nil, err
+				}
+				reauthenticated = true
+				continue
+			}
+		}
+
+		if attempt >= policy.MaxAttempts || !canReplay || !policy.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+		wait, ok := policy.delay(attempt, resp)
+		if !ok {
+			return resp, err
+		}
+		if resp != nil {
+			drainAndClose(resp)
+		}
+		c.log().Warn("retrying request", F("method", req.Method), F("endpoint", req.URL.Path), F("attempt", attempt), F("wait", wait))
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		attempt++
+	}
+}
+
+// drainAndClose discards the rest of a response body so that the
+// connection can be reused.
+func drainAndClose(resp *http.Response) {
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// shouldRetry reports whether a request that produced resp or err may be
+// sent again.
+func (p RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	idempotent := req.Method != http.MethodPost || p.RetryNonIdempotent
+	if err != nil {
+		if req.Context().Err() != nil {
+			return false
+		}
+		if isDialError(err) {
+			// Nothing reached the server, so even a POST is safe to resend.
+			return p.RetryNetworkErrors
+		}
+		return p.RetryNetworkErrors && idempotent
+	}
+	if !p.retryableStatus(resp.StatusCode) {
+		return false
+	}
+	if idempotent {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+func (p RetryPolicy) retryableStatus(code int) bool {
+	for _, s := range p.RetryableStatus {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns how long to wait before the next attempt. A Retry-After
+// header on 429 and 503 responses takes precedence over the computed
+// backoff. ok is false if the server asked us to wait longer than
+// MaxRetryAfter.
+func (p RetryPolicy) delay(attempt int, resp *http.Response) (wait time.Duration, ok bool) {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		if d, found := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); found {
+			if p.MaxRetryAfter > 0 && d > p.MaxRetryAfter {
+				return 0, false
+			}
+			return d, true
+		}
+	}
+	return p.backoff(attempt), true
+}
+
+// backoff computes the jittered exponential delay that follows the given
+// attempt number.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d -= d * p.Jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// parseRetryAfter parses a Retry-After header given either as a number of
+// seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	d := t.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,132 @@
+package iconik
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetryPolicy is DefaultRetryPolicy with delays short enough for tests.
+func fastRetryPolicy() *RetryPolicy {
+	p := DefaultRetryPolicy
+	p.InitialBackoff = time.Millisecond
+	p.MaxBackoff = 5 * time.Millisecond
+	return &p
+}
+
+func TestIClient_RetriesTransientStatus(t *testing.T) {
+	var calls int32
+	_, client := newTestServer(t, func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		payload, _ := json.Marshal(GetResponse{Objects: []Object{{URL: "https://test.com/url"}}})
+		rw.Write(payload)
+	})
+	client.RetryPolicy = fastRetryPolicy()
+	if _, err := client.GenerateSignedProxyUrl("testAssetId"); err != nil {
+		t.Fatalf("GenerateSignedProxyUrl() got %v; wanted no error", err)
+	}
+	if calls != 3 {
+		t.Errorf("server got %d calls; wanted 3", calls)
+	}
+}
+
+func TestIClient_NoRetry(t *testing.T) {
+	var calls int32
+	_, client := newTestServer(t, func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	})
+	client.RetryPolicy = fastRetryPolicy()
+	client.NoRetry = true
+	if _, err := client.GenerateSignedProxyUrl("testAssetId"); err == nil {
+		t.Fatalf("GenerateSignedProxyUrl() got no error; wanted one")
+	}
+	if calls != 1 {
+		t.Errorf("server got %d calls; wanted 1", calls)
+	}
+}
+
+func TestIClient_PostNotRetriedOnServerError(t *testing.T) {
+	var calls int32
+	_, client := newTestServer(t, func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		rw.WriteHeader(http.StatusInternalServerError)
+	})
+	client.RetryPolicy = fastRetryPolicy()
+	if _, err := client.CreateCollection("title", "parent"); err == nil {
+		t.Fatalf("CreateCollection() got no error; wanted one")
+	}
+	if calls != 1 {
+		t.Errorf("server got %d calls; wanted 1", calls)
+	}
+}
+
+func TestIClient_PostRetriedWithRetryAfter(t *testing.T) {
+	var calls int32
+	var first time.Time
+	var bodies []string
+	_, client := newTestServer(t, func(rw http.ResponseWriter, req *http.Request) {
+		var body map[string]string
+		json.NewDecoder(req.Body).Decode(&body)
+		bodies = append(bodies, body["title"])
+		if atomic.AddInt32(&calls, 1) == 1 {
+			first = time.Now()
+			rw.Header().Set("Retry-After", "1")
+			rw.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if time.Since(first) < time.Second {
+			t.Errorf("retry arrived after %v; wanted at least 1s", time.Since(first))
+		}
+		rw.WriteHeader(http.StatusCreated)
+		rw.Write([]byte(`{"id": "newID"}`))
+	})
+	client.RetryPolicy = fastRetryPolicy()
+	id, err := client.CreateCollection("title", "parent")
+	if err != nil {
+		t.Fatalf("CreateCollection() got %v; wanted no error", err)
+	}
+	if id != "newID" {
+		t.Errorf("CreateCollection() got %s; wanted newID", id)
+	}
+	if len(bodies) != 2 || bodies[1] != "title" {
+		t.Errorf("server got bodies %v; wanted the request body replayed", bodies)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"-1", 0, false},
+		{"Tue, 01 Mar 2022 12:00:10 GMT", 10 * time.Second, true},
+		{"Tue, 01 Mar 2022 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) got (%v, %v); wanted (%v, %v)", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) got %v; wanted %v", i+1, got, w)
+		}
+	}
+}
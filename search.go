@@ -0,0 +1,393 @@
+package iconik
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Document types that can be searched.
+const (
+	DocTypeAssets      = "assets"
+	DocTypeCollections = "collections"
+)
+
+// Filter operators understood by Iconik's search API.
+const (
+	OperatorAnd = "AND"
+	OperatorOr  = "OR"
+	OperatorNot = "NOT"
+)
+
+// Condition is one clause of a SearchQuery: either a single term or a group
+// of conditions joined by an operator.
+type Condition struct {
+	term  *FilterTerm
+	group *SearchFilter
+}
+
+// Metadata returns the search field name of a custom metadata field, e.g.
+// Metadata("_gcvi_tags") is "metadata._gcvi_tags".
+func Metadata(field string) string {
+	return "metadata." + field
+}
+
+// Match matches documents whose field equals value exactly. Lucene special
+// characters in value are escaped.
+func Match(field, value string) Condition {
+	return Condition{term: &FilterTerm{Name: field, Value: escapeLucene(value)}}
+}
+
+// Wildcard matches documents whose field matches pattern, where '*' matches
+// any sequence of characters and '?' any single character. All other Lucene
+// special characters are escaped.
+func Wildcard(field, pattern string) Condition {
+	return Condition{term: &FilterTerm{Name: field, Value: escapeLuceneWildcard(pattern)}}
+}
+
+// AnyOf matches documents whose field equals one of values.
+func AnyOf(field string, values ...string) Condition {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = escapeLucene(v)
+	}
+	return Condition{term: &FilterTerm{Name: field, ValueIn: escaped}}
+}
+
+// Exists matches documents that have a value for field.
+func Exists(field string) Condition {
+	exists := true
+	return Condition{term: &FilterTerm{Name: field, Exists: &exists}}
+}
+
+// DateRange matches documents whose date field lies between from and to,
+// inclusive. A zero time leaves that end of the range open.
+func DateRange(field string, from, to time.Time) Condition {
+	r := &FilterRange{}
+	if !from.IsZero() {
+		r.Min = from.UTC().Format(time.RFC3339)
+	}
+	if !to.IsZero() {
+		r.Max = to.UTC().Format(time.RFC3339)
+	}
+	return Condition{term: &FilterTerm{Name: field, Range: r}}
+}
+
+// NumberRange matches documents whose numeric field lies between min and
+// max, inclusive.
+func NumberRange(field string, min, max float64) Condition {
+	return Condition{term: &FilterTerm{Name: field, Range: &FilterRange{
+		Min: strconv.FormatFloat(min, 'f', -1, 64),
+		Max: strconv.FormatFloat(max, 'f', -1, 64),
+	}}}
+}
+
+// And matches documents that satisfy every condition.
+func And(conds ...Condition) Condition {
+	return group(OperatorAnd, conds)
+}
+
+// Or matches documents that satisfy at least one condition.
+func Or(conds ...Condition) Condition {
+	return group(OperatorOr, conds)
+}
+
+// Not matches documents that satisfy none of the conditions.
+func Not(conds ...Condition) Condition {
+	return group(OperatorNot, conds)
+}
+
+func group(operator string, conds []Condition) Condition {
+	f := &SearchFilter{Operator: operator, Terms: []FilterTerm{}}
+	for _, c := range conds {
+		c.addTo(f)
+	}
+	return Condition{group: f}
+}
+
+func (c Condition) addTo(f *SearchFilter) {
+	switch {
+	case c.term != nil:
+		f.Terms = append(f.Terms, *c.term)
+	case c.group != nil:
+		f.Filters = append(f.Filters, *c.group)
+	}
+}
+
+// SearchQuery builds the criteria of an Iconik search. The zero value is not
+// usable; create one with NewSearchQuery.
+//
+//	q := iconik.NewSearchQuery().
+//		Where(iconik.Match(iconik.Metadata("_gcvi_tags"), "Teaching")).
+//		Where(iconik.Not(iconik.Match("status", "DELETED")))
+type SearchQuery struct {
+	docTypes []string
+	text     string
+	filter   SearchFilter
+	perPage  int
+	page     int
+
+	sort          []SortField
+	includeFields []string
+	facets        []string
+}
+
+// NewSearchQuery creates a query for assets that matches everything until
+// conditions are added.
+func NewSearchQuery() *SearchQuery {
+	return &SearchQuery{
+		docTypes: []string{DocTypeAssets},
+		filter:   SearchFilter{Operator: OperatorAnd, Terms: []FilterTerm{}},
+	}
+}
+
+// DocTypes sets the document types to search, e.g. DocTypeCollections.
+func (q *SearchQuery) DocTypes(types ...string) *SearchQuery {
+	q.docTypes = types
+	return q
+}
+
+// Text sets a free-text query matched against all indexed fields. It is
+// passed to Iconik as is, so it may use Lucene syntax.
+func (q *SearchQuery) Text(query string) *SearchQuery {
+	q.text = query
+	return q
+}
+
+// Where adds conditions that every result must satisfy.
+func (q *SearchQuery) Where(conds ...Condition) *SearchQuery {
+	for _, c := range conds {
+		c.addTo(&q.filter)
+	}
+	return q
+}
+
+// SortBy orders results by field. Calling it again adds tie-breakers in
+// order of precedence.
+func (q *SearchQuery) SortBy(field string, order SortOrder) *SearchQuery {
+	q.sort = append(q.sort, SortField{Name: field, Order: order})
+	return q
+}
+
+// IncludeFields restricts each returned object to the given fields, which
+// shrinks responses considerably. Fields not listed are left empty in the
+// results.
+func (q *SearchQuery) IncludeFields(fields ...string) *SearchQuery {
+	q.includeFields = append(q.includeFields, fields...)
+	return q
+}
+
+// Facets requests counts of results per distinct value of each field, e.g.
+// Metadata("_gcvi_tags") or "media_type". They are returned in
+// SearchResponse.Facets.
+func (q *SearchQuery) Facets(fields ...string) *SearchQuery {
+	q.facets = append(q.facets, fields...)
+	return q
+}
+
+// PerPage sets how many results are fetched per request. Values below one
+// mean defaultSearchPerPage.
+func (q *SearchQuery) PerPage(n int) *SearchQuery {
+	q.perPage = n
+	return q
+}
+
+// FromPage makes the search start at the given 1-based page, e.g. to
+// resume an interrupted iteration from SearchIterator.Page.
+func (q *SearchQuery) FromPage(page int) *SearchQuery {
+	q.page = page
+	return q
+}
+
+// Criteria returns the request body for the query.
+func (q *SearchQuery) Criteria() SearchCriteriaSchema {
+	return SearchCriteriaSchema{
+		DocTypes:      q.docTypes,
+		Query:         q.text,
+		Filter:        q.filter,
+		Sort:          q.sort,
+		IncludeFields: q.includeFields,
+		Facets:        q.facets,
+	}
+}
+
+// escapeLuceneWildcard is like escapeLucene but leaves the wildcards '*'
+// and '?' intact.
+func escapeLuceneWildcard(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r == '*' || r == '?' {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteString(escapeLucene(string(r)))
+	}
+	return b.String()
+}
+
+// defaultSearchPerPage is the page size used when a query does not set one.
+const defaultSearchPerPage = 100
+
+// Search runs query and returns the matching objects from every page of
+// results. For large result sets, prefer SearchIter, which holds only one
+// page in memory.
+func (c *IClient) Search(ctx context.Context, query *SearchQuery) (*SearchResponse, error) {
+	return c.searchAll(c.SearchIter(ctx, query))
+}
+
+// searchAll drains it into a single response.
+func (c *IClient) searchAll(it *SearchIterator) (*SearchResponse, error) {
+	var allObjects []IconikObject
+	for it.Next() {
+		allObjects = append(allObjects, it.Object())
+	}
+	if err := it.Err(); err != nil {
+		return &SearchResponse{}, err
+	}
+
+	total := it.Total()
+	if total < len(allObjects) {
+		total = len(allObjects)
+	}
+	return &SearchResponse{
+		Objects: allObjects,
+		Page:    it.page,
+		Pages:   it.pages,
+		PerPage: it.perPage,
+		Total:   total,
+		Facets:  it.Facets(),
+	}, nil
+}
+
+// SearchIterator walks the results of a search, fetching each page only
+// when the previous one has been consumed. Stopping early is as simple as
+// not calling Next again.
+//
+//	it := client.SearchIter(ctx, query)
+//	for it.Next() {
+//		obj := it.Object()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type SearchIterator struct {
+	c        *IClient
+	ctx      context.Context
+	criteria SearchCriteriaSchema
+	perPage  int
+
+	page    int // page that objects came from; 0 before the first fetch
+	pages   int
+	total   int
+	facets  map[string]Facet
+	objects []IconikObject
+	index   int
+	last    bool
+	err     error
+}
+
+// SearchIter returns an iterator over the results of query. No request is
+// made until the first call to Next.
+func (c *IClient) SearchIter(ctx context.Context, query *SearchQuery) *SearchIterator {
+	return c.newSearchIterator(ctx, query.Criteria(), query.perPage, query.page)
+}
+
+func (c *IClient) newSearchIterator(ctx context.Context, criteria SearchCriteriaSchema, perPage, page int) *SearchIterator {
+	if perPage < 1 {
+		perPage = defaultSearchPerPage
+	}
+	if page < 1 {
+		page = 1
+	}
+	return &SearchIterator{
+		c:        c,
+		ctx:      ctx,
+		criteria: criteria,
+		perPage:  perPage,
+		page:     page - 1,
+		index:    -1,
+	}
+}
+
+// Next advances to the next object, fetching another page if needed. It
+// returns false when the results are exhausted or a request failed; check
+// Err to tell the two apart.
+func (it *SearchIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	for it.index+1 >= len(it.objects) {
+		if it.last {
+			return false
+		}
+		if err := it.fetch(); err != nil {
+			it.err = err
+			return false
+		}
+	}
+	it.index++
+	return true
+}
+
+func (it *SearchIterator) fetch() error {
+	page := it.page + 1
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(it.perPage))
+	it.c.log().Debug("Search", F("endpoint", searchEndpoint), F("page", page))
+	resp, err := doJSON[SearchResponse](it.ctx, it.c, apiCall{method: http.MethodPost, path: searchEndpoint, query: query, body: it.criteria})
+	if err != nil {
+		return err
+	}
+	it.page = page
+	it.pages = resp.Pages
+	it.total = resp.Total
+	if it.facets == nil {
+		it.facets = resp.Facets
+	}
+	it.objects = resp.Objects
+	it.index = -1
+	it.last = page >= resp.Pages || len(resp.Objects) == 0
+	return nil
+}
+
+// Object returns the current object. It is only valid after Next returned
+// true.
+func (it *SearchIterator) Object() IconikObject {
+	if it.index < 0 || it.index >= len(it.objects) {
+		return IconikObject{}
+	}
+	return it.objects[it.index]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *SearchIterator) Err() error {
+	return it.err
+}
+
+// Total returns the number of matching objects reported by the server. It
+// is zero until the first page has been fetched.
+func (it *SearchIterator) Total() int {
+	return it.total
+}
+
+// Facets returns the facet counts from the first page fetched, or nil if
+// none were requested.
+func (it *SearchIterator) Facets() map[string]Facet {
+	return it.facets
+}
+
+// Page returns the 1-based page the current object came from. Passing it to
+// SearchQuery.FromPage resumes the search at that page.
+func (it *SearchIterator) Page() int {
+	return it.page
+}
+
+// Pages returns the number of pages reported by the server.
+func (it *SearchIterator) Pages() int {
+	return it.pages
+}
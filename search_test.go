@@ -0,0 +1,216 @@
+package iconik
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSearchQuery_Criteria(t *testing.T) {
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := NewSearchQuery().
+		DocTypes(DocTypeCollections).
+		Text("lecture").
+		Where(Match(Metadata("_gcvi_tags"), "Teaching Lab")).
+		Where(Or(Wildcard("title", "CS 101*"), Not(Match("status", "DELETED")))).
+		Where(DateRange("date_created", from, time.Time{}), NumberRange("size", 1, 2.5)).
+		Where(AnyOf("type", "VIDEO", "AUDIO"), Exists("description"))
+
+	exists := true
+	want := SearchCriteriaSchema{
+		DocTypes: []string{"collections"},
+		Query:    "lecture",
+		Filter: SearchFilter{
+			Operator: "AND",
+			Terms: []FilterTerm{
+				{Name: "metadata._gcvi_tags", Value: `Teaching\ Lab`},
+				{Name: "date_created", Range: &FilterRange{Min: "2021-01-01T00:00:00Z"}},
+				{Name: "size", Range: &FilterRange{Min: "1", Max: "2.5"}},
+				{Name: "type", ValueIn: []string{"VIDEO", "AUDIO"}},
+				{Name: "description", Exists: &exists},
+			},
+			Filters: []SearchFilter{{
+				Operator: "OR",
+				Terms:    []FilterTerm{{Name: "title", Value: `CS\ 101*`}},
+				Filters: []SearchFilter{{
+					Operator: "NOT",
+					Terms:    []FilterTerm{{Name: "status", Value: "DELETED"}},
+				}},
+			}},
+		},
+	}
+	if got := q.Criteria(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Criteria() got %+v; wanted %+v", got, want)
+	}
+}
+
+func TestEscapeLuceneWildcard(t *testing.T) {
+	tests := map[string]string{
+		"abc*":      "abc*",
+		"a?c":       "a?c",
+		"(draft)*":  `\(draft\)*`,
+		"one two?":  `one\ two?`,
+		`back\sla*`: `back\\sla*`,
+	}
+	for in, want := range tests {
+		if got := escapeLuceneWildcard(in); got != want {
+			t.Errorf("escapeLuceneWildcard(%q) got %q; wanted %q", in, got, want)
+		}
+	}
+}
+
+func TestIClient_Search(t *testing.T) {
+	var bodies []SearchCriteriaSchema
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body SearchCriteriaSchema
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding search body: %v", err)
+		}
+		bodies = append(bodies, body)
+		page := r.URL.Query().Get("page")
+		json.NewEncoder(w).Encode(SearchResponse{
+			Objects: []IconikObject{{Id: "asset-" + page}},
+			Page:    len(bodies),
+			Pages:   2,
+		})
+	})
+	res, err := client.Search(context.Background(), NewSearchQuery().Where(Match("title", "x")))
+	if err != nil {
+		t.Fatalf("Search() got error %v", err)
+	}
+	if len(res.Objects) != 2 || res.Objects[0].Id != "asset-1" || res.Objects[1].Id != "asset-2" {
+		t.Errorf("Search() got objects %+v; wanted asset-1 and asset-2", res.Objects)
+	}
+	if len(bodies) != 2 || bodies[0].Filter.Terms[0].Value != "x" {
+		t.Errorf("Search() sent %+v; wanted two requests filtering on title", bodies)
+	}
+}
+
+// pagedSearchServer serves pages of single-letter object IDs from objects,
+// recording the pages requested.
+func pagedSearchServer(t *testing.T, objects string, requested *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		*requested = append(*requested, r.URL.Query().Encode())
+		pages := (len(objects) + perPage - 1) / perPage
+		resp := SearchResponse{Page: page, Pages: pages, PerPage: perPage, Total: len(objects)}
+		for i := (page - 1) * perPage; i < page*perPage && i < len(objects); i++ {
+			resp.Objects = append(resp.Objects, IconikObject{Id: objects[i : i+1]})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func TestIClient_SearchIter(t *testing.T) {
+	var requested []string
+	_, client := newTestServer(t, pagedSearchServer(t, "abcde", &requested))
+
+	it := client.SearchIter(context.Background(), NewSearchQuery().PerPage(2))
+	if len(requested) != 0 {
+		t.Errorf("SearchIter() made %d requests before Next; wanted 0", len(requested))
+	}
+	var got string
+	for it.Next() {
+		got += it.Object().Id
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("SearchIter() got error %v", err)
+	}
+	if got != "abcde" {
+		t.Errorf("SearchIter() got objects %q; wanted %q", got, "abcde")
+	}
+	if it.Total() != 5 || it.Pages() != 3 {
+		t.Errorf("SearchIter() got Total %d, Pages %d; wanted 5, 3", it.Total(), it.Pages())
+	}
+	want := []string{"page=1&per_page=2", "page=2&per_page=2", "page=3&per_page=2"}
+	if !reflect.DeepEqual(requested, want) {
+		t.Errorf("SearchIter() requested %v; wanted %v", requested, want)
+	}
+}
+
+func TestIClient_SearchIterEarlyStopAndResume(t *testing.T) {
+	var requested []string
+	_, client := newTestServer(t, pagedSearchServer(t, "abcde", &requested))
+
+	it := client.SearchIter(context.Background(), NewSearchQuery().PerPage(2))
+	for it.Next() {
+		if it.Object().Id == "c" {
+			break
+		}
+	}
+	if len(requested) != 2 || it.Page() != 2 {
+		t.Fatalf("SearchIter() stopped after %d requests on page %d; wanted 2 and 2", len(requested), it.Page())
+	}
+
+	var got string
+	resumed := client.SearchIter(context.Background(), NewSearchQuery().PerPage(2).FromPage(it.Page()))
+	for resumed.Next() {
+		got += resumed.Object().Id
+	}
+	if got != "cde" || resumed.Err() != nil {
+		t.Errorf("SearchIter() resumed got %q, %v; wanted %q, nil", got, resumed.Err(), "cde")
+	}
+}
+
+func TestIClient_SearchIterError(t *testing.T) {
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":["bad query"]}`, http.StatusBadRequest)
+	})
+
+	it := client.SearchIter(context.Background(), NewSearchQuery())
+	if it.Next() {
+		t.Errorf("Next() got true; wanted false")
+	}
+	var apiErr *APIError
+	if !errors.As(it.Err(), &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Err() got %v; wanted a 400 APIError", it.Err())
+	}
+}
+
+func TestIClient_SearchSortFieldsAndFacets(t *testing.T) {
+	var body map[string]interface{}
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"objects":[{"id":"a","title":"A"}],"page":1,"pages":1,"total":1,
+			"facets":{"media_type":{"buckets":[{"key":"video","doc_count":3}]},
+			          "size":{"buckets":[{"key":1024,"doc_count":2}]}}}`))
+	})
+
+	q := NewSearchQuery().
+		SortBy("date_created", SortDesc).
+		SortBy("title", SortAsc).
+		IncludeFields("id", "title").
+		Facets("media_type", "size")
+	res, err := client.Search(context.Background(), q)
+	if err != nil {
+		t.Fatalf("Search() got error %v", err)
+	}
+
+	wantBody := map[string]interface{}{
+		"sort": []interface{}{
+			map[string]interface{}{"name": "date_created", "order": "desc"},
+			map[string]interface{}{"name": "title", "order": "asc"},
+		},
+		"include_fields": []interface{}{"id", "title"},
+		"facets":         []interface{}{"media_type", "size"},
+	}
+	for k, want := range wantBody {
+		if !reflect.DeepEqual(body[k], want) {
+			t.Errorf("Search() sent %s = %v; wanted %v", k, body[k], want)
+		}
+	}
+
+	wantFacets := map[string]Facet{
+		"media_type": {Buckets: []FacetBucket{{Key: "video", DocCount: 3}}},
+		"size":       {Buckets: []FacetBucket{{Key: "1024", DocCount: 2}}},
+	}
+	if !reflect.DeepEqual(res.Facets, wantFacets) {
+		t.Errorf("Search() got facets %+v; wanted %+v", res.Facets, wantFacets)
+	}
+}
@@ -0,0 +1,172 @@
+package iconik
+
+import (
+	"context"
+	"fmt"
+)
+
+// TagField is the metadata field holding an asset's tags.
+const TagField = "_gcvi_tags"
+
+// TagTarget selects the assets a bulk tag operation applies to: every asset
+// in AssetIDs plus every asset matching Query. Assets selected by both are
+// processed once.
+type TagTarget struct {
+	AssetIDs []string
+	Query    *SearchQuery
+}
+
+// TagOptions configures a bulk tag operation.
+type TagOptions struct {
+	// ViewID is the metadata view containing the tag field. It is required.
+	ViewID string
+	// Field is the tag field; empty means TagField.
+	Field string
+	// DryRun reports the changes that would be made without making them.
+	DryRun bool
+}
+
+// TagChange describes how one asset's tags were, or in a dry run would be,
+// changed.
+type TagChange struct {
+	AssetID string
+	Before  []string
+	After   []string
+}
+
+// AddTags adds tags to every target asset that lacks them.
+func (c *IClient) AddTags(ctx context.Context, target TagTarget, tags []string, opts TagOptions) ([]TagChange, error) {
+	return c.updateTags(ctx, target, opts, func(current []string) []string {
+		out := append([]string(nil), current...)
+		for _, tag := range tags {
+			if !containsString(out, tag) {
+				out = append(out, tag)
+			}
+		}
+		return out
+	})
+}
+
+// RemoveTags removes tags from every target asset that has them.
+func (c *IClient) RemoveTags(ctx context.Context, target TagTarget, tags []string, opts TagOptions) ([]TagChange, error) {
+	return c.updateTags(ctx, target, opts, func(current []string) []string {
+		out := []string{}
+		for _, tag := range current {
+			if !containsString(tags, tag) {
+				out = append(out, tag)
+			}
+		}
+		return out
+	})
+}
+
+// ReplaceTags sets the tags of every target asset to exactly tags.
+func (c *IClient) ReplaceTags(ctx context.Context, target TagTarget, tags []string, opts TagOptions) ([]TagChange, error) {
+	return c.updateTags(ctx, target, opts, func([]string) []string {
+		out := []string{}
+		for _, tag := range tags {
+			if !containsString(out, tag) {
+				out = append(out, tag)
+			}
+		}
+		return out
+	})
+}
+
+// updateTags applies edit to the tags of every target asset and returns the
+// assets whose tags changed. On error, the changes made so far are
+// returned along with it.
+func (c *IClient) updateTags(ctx context.Context, target TagTarget, opts TagOptions, edit func([]string) []string) ([]TagChange, error) {
+	if opts.ViewID == "" {
+		return nil, &IError{Errors: []string{"a metadata view ID is required to change tags"}}
+	}
+	field := opts.Field
+	if field == "" {
+		field = TagField
+	}
+	view, err := c.GetMetadataView(ctx, opts.ViewID)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := c.tagTargetIDs(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []TagChange
+	for _, id := range ids {
+		md, err := c.assetMetadata(ctx, id, view)
+		if err != nil {
+			return changes, fmt.Errorf("reading tags of asset %s: %w", id, err)
+		}
+		before := make([]string, 0, len(md[field]))
+		for _, v := range md[field] {
+			if s, ok := v.(string); ok {
+				before = append(before, s)
+			}
+		}
+		after := edit(before)
+		if equalStrings(before, after) {
+			continue
+		}
+		if !opts.DryRun {
+			values := make([]interface{}, len(after))
+			for i, tag := range after {
+				values[i] = tag
+			}
+			if err := c.updateAssetMetadata(ctx, id, view, MetadataValues{field: values}); err != nil {
+				return changes, fmt.Errorf("updating tags of asset %s: %w", id, err)
+			}
+		}
+		c.log().Info("tags changed", F("asset_id", id), F("before", before), F("after", after), F("dry_run", opts.DryRun))
+		changes = append(changes, TagChange{AssetID: id, Before: before, After: after})
+	}
+	return changes, nil
+}
+
+// tagTargetIDs returns the IDs of the assets target selects, without
+// duplicates.
+func (c *IClient) tagTargetIDs(ctx context.Context, target TagTarget) ([]string, error) {
+	seen := make(map[string]bool)
+	var ids []string
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range target.AssetIDs {
+		add(id)
+	}
+	if target.Query != nil {
+		it := c.SearchIter(ctx, target.Query)
+		for it.Next() {
+			add(it.Object().Id)
+		}
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package iconik
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// tagServer serves one metadata view with a tag field and stores the tags
+// of assets in tags.
+func tagServer(t *testing.T, tags map[string][]string) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/metadata/v1/views/v1/":
+			w.Write([]byte(`{"id":"v1","view_fields":[{"name":"_gcvi_tags","field_type":"tag_cloud","multi":true}]}`))
+		case r.URL.Path == "/search/v1/search/":
+			w.Write([]byte(`{"objects":[{"id":"a2"},{"id":"a3"}],"page":1,"pages":1}`))
+		case strings.HasPrefix(r.URL.Path, "/metadata/v1/assets/"):
+			id := strings.Split(r.URL.Path, "/")[4]
+			var body metadataValuesSchema
+			if r.Method == http.MethodPut {
+				json.NewDecoder(r.Body).Decode(&body)
+				tags[id] = nil
+				for _, v := range body.MetadataValues[TagField].FieldValues {
+					tags[id] = append(tags[id], v.Value.(string))
+				}
+				return
+			}
+			body.MetadataValues = map[string]metadataFieldValues{TagField: {}}
+			for _, tag := range tags[id] {
+				fv := body.MetadataValues[TagField]
+				fv.FieldValues = append(fv.FieldValues, metadataFieldValue{Value: tag})
+				body.MetadataValues[TagField] = fv
+			}
+			json.NewEncoder(w).Encode(body)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}
+}
+
+func TestIClient_BulkTags(t *testing.T) {
+	tags := map[string][]string{
+		"a1": {"Teaching"},
+		"a2": {"Lab"},
+		"a3": {"Teaching", "Lab"},
+	}
+	_, client := newTestServer(t, tagServer(t, tags))
+	ctx := context.Background()
+	opts := TagOptions{ViewID: "v1"}
+	target := TagTarget{AssetIDs: []string{"a1", "a2"}, Query: NewSearchQuery()}
+
+	changes, err := client.AddTags(ctx, target, []string{"Teaching"}, opts)
+	if err != nil {
+		t.Fatalf("AddTags() got error %v", err)
+	}
+	wantChanges := []TagChange{{AssetID: "a2", Before: []string{"Lab"}, After: []string{"Lab", "Teaching"}}}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("AddTags() got changes %+v; wanted %+v", changes, wantChanges)
+	}
+
+	changes, err = client.RemoveTags(ctx, target, []string{"Lab"}, opts)
+	if err != nil {
+		t.Fatalf("RemoveTags() got error %v", err)
+	}
+	if len(changes) != 2 {
+		t.Errorf("RemoveTags() got changes %+v; wanted a2 and a3", changes)
+	}
+
+	changes, err = client.ReplaceTags(ctx, TagTarget{AssetIDs: []string{"a1"}}, []string{"Archive"}, TagOptions{ViewID: "v1", DryRun: true})
+	if err != nil {
+		t.Fatalf("ReplaceTags() got error %v", err)
+	}
+	wantChanges = []TagChange{{AssetID: "a1", Before: []string{"Teaching"}, After: []string{"Archive"}}}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("ReplaceTags() dry run got changes %+v; wanted %+v", changes, wantChanges)
+	}
+
+	want := map[string][]string{
+		"a1": {"Teaching"},
+		"a2": {"Teaching"},
+		"a3": {"Teaching"},
+	}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags got %v; wanted %v", tags, want)
+	}
+}
+
+func TestIClient_BulkTagsNeedsView(t *testing.T) {
+	client := newTestClient(t, "http://127.0.0.1:0")
+	if _, err := client.AddTags(context.Background(), TagTarget{AssetIDs: []string{"a1"}}, []string{"x"}, TagOptions{}); err == nil {
+		t.Errorf("AddTags() without a view got nil error")
+	}
+}
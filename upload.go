@@ -0,0 +1,524 @@
+package iconik
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UploadOptions describes an asset created by UploadFile.
+type UploadOptions struct {
+	// CollectionID is the collection the asset is added to. It is required.
+	CollectionID string
+	// FileName is the original name of the file. It is required.
+	FileName string
+	// Title is the asset title; empty means the base name of FileName.
+	Title string
+	// StoragePath is the directory in the storage the file is saved to;
+	// empty means "/".
+	StoragePath string
+	// MimeType is the file's media type; empty means it is detected from
+	// the first 512 bytes.
+	MimeType string
+	// DateCreated is recorded as the file's creation date; zero means now.
+	DateCreated time.Time
+	// PartSize is the size of each part of a multipart upload, between
+	// MinPartSize and MaxPartSize; zero means MULTIPART_FILESIZE_THRESHOLD.
+	// Files no larger than one part are uploaded in a single request. For
+	// files that would need more than MaxParts parts, the part size is
+	// raised as needed.
+	PartSize int64
+	// Concurrency is how many parts are uploaded at once; zero means
+	// DefaultUploadConcurrency. Parts are streamed from the file, so memory
+	// use does not grow with the part size.
+	Concurrency int
+	// CheckpointPath, if set, is a file where the progress of the upload is
+	// saved, so that ResumeUpload can finish it after a crash. It is
+	// removed once the upload completes. The file holds upload
+	// credentials and is only readable by its owner.
+	CheckpointPath string
+	// CheckpointMaxAge is how old a checkpoint may be before ResumeUpload
+	// treats it as stale; zero means DefaultCheckpointMaxAge.
+	CheckpointMaxAge time.Duration
+	// Progress, if set, is called after each part is sent and every
+	// ProgressInterval in between. Calls are never concurrent.
+	Progress func(UploadProgress)
+	// ProgressInterval is how often Progress is called and the upload's job
+	// has its progress updated; zero means DefaultProgressInterval.
+	ProgressInterval time.Duration
+}
+
+// Limits of the storage provider's multipart uploads.
+const (
+	MinPartSize = 5 * 1024 * 1024
+	MaxPartSize = 5 * 1024 * 1024 * 1024
+	MaxParts    = 10000
+)
+
+// DefaultUploadConcurrency is the number of parts UploadFile uploads at
+// once by default.
+const DefaultUploadConcurrency = 4
+
+const multipartPartUrlEndpointTemplate = "files/v1/assets/%s/files/%s/multipart/b2/url/"
+
+// partSize returns the part size to use for a file of size bytes.
+func (opts UploadOptions) partSize(size int64) (int64, error) {
+	partSize := opts.PartSize
+	if partSize == 0 {
+		partSize = MULTIPART_FILESIZE_THRESHOLD
+	}
+	if partSize < MinPartSize || partSize > MaxPartSize {
+		return 0, &IError{Errors: []string{fmt.Sprintf("part size %d is outside %d to %d", partSize, MinPartSize, MaxPartSize)}}
+	}
+	if min := (size + MaxParts - 1) / MaxParts; partSize < min {
+		partSize = min
+	}
+	if partSize > MaxPartSize {
+		return 0, &IError{Errors: []string{fmt.Sprintf("file of %d bytes is too large to upload", size)}}
+	}
+	return partSize, nil
+}
+
+// UploadFile creates an asset from the size bytes of r: it creates the
+// asset and its upload target with MakeNewAsset, transfers the bytes to
+// storage, single-part or multipart depending on size, and completes the
+// upload as FinishUpload does. It returns the ID of the new asset.
+//
+// If the upload fails before its file is complete, the asset is deleted
+// and its job failed, or aborted if ctx was cancelled; with
+// opts.CheckpointPath set, it is instead left for ResumeUpload to finish.
+// Once the file is complete, a later failure, such as requesting keyframes,
+// keeps the asset and its ID is returned with the error.
+func (c *IClient) UploadFile(ctx context.Context, r io.ReaderAt, size int64, opts UploadOptions) (string, error) {
+	if opts.CollectionID == "" || opts.FileName == "" {
+		return "", &IError{Errors: []string{"UploadFile: CollectionID and FileName are required"}}
+	}
+	if opts.Title == "" {
+		opts.Title = path.Base(opts.FileName)
+	}
+	if opts.StoragePath == "" {
+		opts.StoragePath = "/"
+	}
+	if opts.DateCreated.IsZero() {
+		opts.DateCreated = time.Now()
+	}
+	if opts.MimeType == "" {
+		mimeType, err := detectMimeType(r, size)
+		if err != nil {
+			return "", err
+		}
+		opts.MimeType = mimeType
+	}
+
+	partSize, err := opts.partSize(size)
+	if err != nil {
+		return "", err
+	}
+	if opts.CheckpointPath != "" {
+		if _, err := os.Stat(opts.CheckpointPath); err == nil {
+			return "", &IError{Errors: []string{fmt.Sprintf("checkpoint %s already exists; finish it with ResumeUpload or remove it", opts.CheckpointPath)}}
+		}
+	}
+
+	NAU, err := c.makeNewAsset(ctx, opts.CollectionID, opts.Title, opts.StoragePath, opts.MimeType, size, opts.DateCreated, size > partSize)
+	if err != nil {
+		return "", err
+	}
+	u := &upload{c: c, NAU: NAU, r: r, size: size, partSize: partSize, concurrency: opts.Concurrency,
+		onProgress: opts.Progress, progressInterval: opts.ProgressInterval}
+	if opts.CheckpointPath != "" {
+		if u.checkpoint, err = newCheckpointFile(opts.CheckpointPath, NAU, r, size, partSize); err != nil {
+			u.abandon(ctx)
+			return "", fmt.Errorf("saving checkpoint for asset %s: %w", NAU.AssetID, err)
+		}
+	}
+	return u.run(ctx)
+}
+
+// upload is the state of one transfer started by UploadFile or continued
+// by ResumeUpload.
+type upload struct {
+	c           *IClient
+	NAU         *NewAssetUpload
+	r           io.ReaderAt
+	size        int64
+	partSize    int64
+	concurrency int
+	// resumed is set when continuing from a checkpoint, whose upload URL
+	// may have expired.
+	resumed bool
+	// checkpoint records progress; nil if none was requested.
+	checkpoint *checkpointFile
+	// finished records the steps of finishing the upload already done.
+	finished finishState
+
+	onProgress       func(UploadProgress)
+	progressInterval time.Duration
+	progress         *progressTracker
+}
+
+// numParts returns how many parts a multipart upload has.
+func (u *upload) numParts() int {
+	return int((u.size + u.partSize - 1) / u.partSize)
+}
+
+// partBounds returns the offsets of the start and end of part i, counting
+// from 0.
+func (u *upload) partBounds(i int) (start, end int64) {
+	start = int64(i) * u.partSize
+	end = start + u.partSize
+	if end > u.size {
+		end = u.size
+	}
+	return start, end
+}
+
+// run transfers whatever has not been transferred yet and finishes the
+// upload.
+func (u *upload) run(ctx context.Context) (string, error) {
+	if u.checkpoint == nil || !u.checkpoint.transferred() {
+		if err := u.transfer(ctx); err != nil {
+			u.abandon(ctx)
+			return "", fmt.Errorf("uploading asset %s: %w", u.NAU.AssetID, err)
+		}
+	}
+	if err := u.finish(ctx); err != nil {
+		if !u.finished.FileClosed {
+			u.abandon(ctx)
+			return "", err
+		}
+		return u.NAU.AssetID, err
+	}
+	if u.checkpoint != nil {
+		if err := u.checkpoint.remove(); err != nil {
+			u.c.log().Warn("removing upload checkpoint", F("path", u.checkpoint.path), F("error", err))
+		}
+	}
+	return u.NAU.AssetID, nil
+}
+
+// finishState records which steps of finishing an upload are done, so
+// that resuming it does not repeat them.
+type finishState struct {
+	MultipartFinished  bool `json:"multipart_finished,omitempty"`
+	FileClosed         bool `json:"file_closed,omitempty"`
+	KeyframesRequested bool `json:"keyframes_requested,omitempty"`
+}
+
+// finish does what FinishUploadContext does, skipping the steps already
+// done and recording each in the checkpoint as it completes.
+func (u *upload) finish(ctx context.Context) error {
+	NAU := u.NAU
+	steps := []struct {
+		done *bool
+		run  func() error
+	}{
+		{&u.finished.MultipartFinished, func() error {
+			if NAU.MultipartFileID == "" {
+				return nil
+			}
+			return u.c.FinishMultipartUploadContext(ctx, NAU)
+		}},
+		{&u.finished.FileClosed, func() error { return u.c.CloseFileRequestContext(ctx, NAU.AssetID, NAU.FileReqID) }},
+		{&u.finished.KeyframesRequested, func() error { return u.c.GenerateKeyframesContext(ctx, NAU.AssetID, NAU.FileReqID) }},
+	}
+	for _, step := range steps {
+		if *step.done {
+			continue
+		}
+		if err := step.run(); err != nil {
+			return err
+		}
+		*step.done = true
+		if u.checkpoint != nil {
+			if err := u.checkpoint.markFinished(u.finished); err != nil {
+				return err
+			}
+		}
+	}
+	return u.c.FinishJobContext(ctx, NAU.JobID)
+}
+
+// abandon deletes the asset of a failed upload and fails its job, or
+// aborts it if ctx was cancelled. Uploads with a checkpoint are left for
+// ResumeUpload instead.
+func (u *upload) abandon(ctx context.Context) {
+	if u.checkpoint == nil {
+		u.c.discardUpload(ctx, u.NAU)
+	}
+}
+
+// transfer sends the file to storage, reporting progress as it goes.
+func (u *upload) transfer(ctx context.Context) error {
+	multipart := u.NAU.MultipartFileID != ""
+	parts, base := 1, int64(0)
+	if multipart {
+		parts = u.numParts()
+		for i := 0; i < parts && u.checkpoint != nil; i++ {
+			if u.checkpoint.part(i+1) != "" {
+				start, end := u.partBounds(i)
+				base += end - start
+			}
+		}
+	}
+	u.progress = newProgressTracker(u.c, u.NAU.JobID, u.size, base, parts, u.onProgress)
+	stop := u.progress.run(ctx, u.progressInterval)
+	defer stop()
+
+	var err error
+	if multipart {
+		err = u.uploadParts(ctx)
+	} else {
+		err = u.uploadSingle(ctx)
+	}
+	if err == nil && u.checkpoint != nil {
+		err = u.checkpoint.markTransferred(u.NAU.Sha1List)
+	}
+	return err
+}
+
+// detectMimeType sniffs the media type of r from its first 512 bytes, the
+// most http.DetectContentType considers.
+func detectMimeType(r io.ReaderAt, size int64) (string, error) {
+	n := int64(512)
+	if size < n {
+		n = size
+	}
+	head := make([]byte, n)
+	if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+		return "", fmt.Errorf("reading file header: %w", err)
+	}
+	return http.DetectContentType(head), nil
+}
+
+// hexDigitsAtEnd is the X-Bz-Content-Sha1 value telling the storage
+// provider that the SHA1 follows the content as 40 hex digits, which lets
+// the hash be computed while the content is streamed.
+const hexDigitsAtEnd = "hex_digits_at_end"
+
+// uploadSingle transfers the whole file in one storage request, streaming
+// it from r and appending its SHA1.
+func (u *upload) uploadSingle(ctx context.Context) error {
+	h := sha1.New()
+	body := io.MultiReader(io.TeeReader(u.progress.counted(io.NewSectionReader(u.r, 0, u.size)), h), &hexSumReader{h: h})
+	header := http.Header{}
+	header.Set("X-Bz-File-Name", url.PathEscape(u.NAU.UploadFilename))
+	header.Set("X-Bz-Content-Sha1", hexDigitsAtEnd)
+	header.Set("Content-Type", u.NAU.MimeType)
+	target := uploadTarget{url: u.NAU.UploadURL, token: u.NAU.UploadAuthToken}
+	if _, err := u.c.storageUpload(ctx, target, body, u.size+sha1.Size*2, header); err != nil {
+		return err
+	}
+	u.progress.report(1)
+	return nil
+}
+
+// hexSumReader reads the hex-encoded sum of h, taken on the first Read.
+type hexSumReader struct {
+	h   hash.Hash
+	sum io.Reader
+}
+
+func (r *hexSumReader) Read(p []byte) (int, error) {
+	if r.sum == nil {
+		r.sum = strings.NewReader(hex.EncodeToString(r.h.Sum(nil)))
+	}
+	return r.sum.Read(p)
+}
+
+// uploadTarget is a URL that file content can be posted to, with the
+// token authorizing it.
+type uploadTarget struct {
+	url   string
+	token string
+}
+
+// multipartPartTarget gets another URL for uploading parts of NAU's
+// multipart upload. Each URL may only be used by one request at a time.
+func (c *IClient) multipartPartTarget(ctx context.Context, NAU *NewAssetUpload) (uploadTarget, error) {
+	endpoint := fmt.Sprintf(multipartPartUrlEndpointTemplate, NAU.AssetID, NAU.FileReqID)
+	body := map[string]string{"upload_file_id": NAU.MultipartFileID}
+	resp, err := doJSON[struct {
+		AuthorizationToken string `json:"authorization_token"`
+		UploadURL          string `json:"upload_url"`
+	}](ctx, c, apiCall{method: http.MethodPost, path: endpoint, body: body})
+	if err != nil {
+		return uploadTarget{}, err
+	}
+	return uploadTarget{url: resp.UploadURL, token: resp.AuthorizationToken}, nil
+}
+
+// uploadParts transfers the file in parts of partSize bytes, up to
+// concurrency at a time, each worker with its own upload URL. Parts the
+// checkpoint records as done are skipped. The parts' SHA1s are recorded in
+// NAU.Sha1List in part order. Each part is read from the file twice, once
+// to hash it and once to send it, so no part is held in memory.
+func (u *upload) uploadParts(ctx context.Context) error {
+	shas := make([]string, u.numParts())
+	var todo []int
+	for i := range shas {
+		if u.checkpoint != nil {
+			shas[i] = u.checkpoint.part(i + 1)
+		}
+		if shas[i] == "" {
+			todo = append(todo, i)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	parts := make(chan int)
+	workers := u.concurrency
+	if workers < 1 {
+		workers = DefaultUploadConcurrency
+	}
+	if len(todo) < workers {
+		workers = len(todo)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			target := uploadTarget{url: u.NAU.UploadURL, token: u.NAU.UploadAuthToken}
+			if w > 0 || u.resumed {
+				var err error
+				if target, err = u.c.multipartPartTarget(ctx, u.NAU); err != nil {
+					fail(fmt.Errorf("getting part upload URL: %w", err))
+					return
+				}
+			}
+			for i := range parts {
+				start, end := u.partBounds(i)
+				sha, err := u.uploadPart(ctx, target, io.NewSectionReader(u.r, start, end-start), i+1)
+				if err == nil && u.checkpoint != nil {
+					err = u.checkpoint.partDone(i+1, sha)
+				}
+				if err != nil {
+					fail(fmt.Errorf("part %d: %w", i+1, err))
+					return
+				}
+				shas[i] = sha
+				u.progress.report(i + 1)
+			}
+		}(w)
+	}
+feed:
+	for _, i := range todo {
+		select {
+		case parts <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(parts)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	u.NAU.Sha1List = shas
+	return nil
+}
+
+// uploadPart sends one part and returns its SHA1, after checking that the
+// storage provider computed the same one.
+func (u *upload) uploadPart(ctx context.Context, target uploadTarget, part *io.SectionReader, partNum int) (string, error) {
+	h := sha1.New()
+	if _, err := io.Copy(h, part); err != nil {
+		return "", fmt.Errorf("reading part: %w", err)
+	}
+	if _, err := part.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	header := http.Header{}
+	header.Set("X-Bz-Part-Number", strconv.Itoa(partNum))
+	header.Set("X-Bz-Content-Sha1", sum)
+	respBody, err := u.c.storageUpload(ctx, target, u.progress.counted(part), part.Size(), header)
+	if err != nil {
+		return "", err
+	}
+	var partResp struct {
+		ContentSha1 string `json:"contentSha1"`
+	}
+	if err := json.Unmarshal(respBody, &partResp); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+	// The finish call sends these SHA1s to the storage provider, so one
+	// that does not match what was sent would corrupt the finished file.
+	if partResp.ContentSha1 != sum {
+		return "", fmt.Errorf("storage provider reported SHA1 %q; wanted %s", partResp.ContentSha1, sum)
+	}
+	return partResp.ContentSha1, nil
+}
+
+// storageDoer returns a copy of the client's http.Client without its
+// timeout. A part can take far longer to send than any API request, so
+// storage requests are bounded only by their context.
+func (c *IClient) storageDoer() *http.Client {
+	hc := *c.httpDoer()
+	hc.Timeout = 0
+	return &hc
+}
+
+// storageUpload posts length bytes of body to target. These requests go to
+// the storage provider rather than Iconik, so they carry the upload token
+// instead of the client's credentials.
+func (c *IClient) storageUpload(ctx context.Context, target uploadTarget, body io.Reader, length int64, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = length
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Authorization", target.token)
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	start := time.Now()
+	resp, err := c.storageDoer().Do(req)
+	c.logAttempt(req, resp, err, 1, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.logResponse(resp, respBody)
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiErrorFromResponse(resp, respBody)
+	}
+	return respBody, nil
+}
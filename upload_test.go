@@ -0,0 +1,421 @@
+package iconik
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// storagePart is one request received by a fakeUploadServer's storage
+// endpoint.
+type storagePart struct {
+	header http.Header
+	body   []byte
+}
+
+// fakeUploadServer plays both Iconik and the storage provider for uploads.
+type fakeUploadServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	calls    []string      // Iconik requests, as "METHOD path"
+	bodies   [][]byte      // bodies of calls
+	parts    []storagePart // storage requests
+	failPart int           // part number to reject; 0 rejects none
+	shaPart  int           // part number answered with badSha; 0 answers none
+	badSha   string        // contentSha1 returned for shaPart
+	failCall string        // substring of Iconik requests to reject
+	slowPart int           // part number held back until the others arrive
+	others   int           // how many other parts slowPart waits for
+}
+
+// newFakeUploadServer starts a fakeUploadServer that runs until the test
+// ends.
+func newFakeUploadServer(t *testing.T) *fakeUploadServer {
+	s := &fakeUploadServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		slow := s.slowPart != 0 && r.Header.Get("X-Bz-Part-Number") == strconv.Itoa(s.slowPart)
+		s.mu.Unlock()
+		for deadline := time.Now().Add(5 * time.Second); slow && time.Now().Before(deadline); {
+			s.mu.Lock()
+			arrived := len(s.parts)
+			s.mu.Unlock()
+			if arrived >= s.others {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if r.URL.Path == "/storage/upload" {
+			if auth := r.Header.Get("Authorization"); auth != "upload-token" {
+				t.Errorf("storage request got Authorization %q; wanted the upload token", auth)
+			}
+			if r.Header.Get("Auth-Token") != "" || r.Header.Get("App-Id") != "" {
+				t.Errorf("storage request carried Iconik credentials")
+			}
+			s.parts = append(s.parts, storagePart{header: r.Header.Clone(), body: body})
+			if part := r.Header.Get("X-Bz-Part-Number"); part != "" && part == strconv.Itoa(s.failPart) {
+				http.Error(w, `{"code":"bad_request","message":"checksum mismatch"}`, http.StatusBadRequest)
+				return
+			}
+			sum := sha1.Sum(body)
+			contentSha1 := hex.EncodeToString(sum[:])
+			if part := r.Header.Get("X-Bz-Part-Number"); part != "" && part == strconv.Itoa(s.shaPart) {
+				contentSha1 = s.badSha
+			}
+			json.NewEncoder(w).Encode(map[string]string{"contentSha1": contentSha1})
+			return
+		}
+		s.calls = append(s.calls, r.Method+" "+r.URL.Path)
+		s.bodies = append(s.bodies, body)
+		if s.failCall != "" && strings.Contains(r.Method+" "+r.URL.Path, s.failCall) {
+			http.Error(w, `{"errors":["rejected"]}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":                  "id-" + strings.Trim(strings.ReplaceAll(r.URL.Path, "/", "-"), "-"),
+			"created_by_user":     "user-1",
+			"upload_url":          s.URL + "/storage/upload",
+			"upload_credentials":  map[string]string{"authorizationToken": "upload-token"},
+			"upload_filename":     "lecture one.mp4",
+			"upload_file_id":      "multipart-1",
+			"authorization_token": "upload-token",
+			"objects":             []map[string]string{{"id": "storage-1"}},
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// called reports whether Iconik received a request whose "METHOD path"
+// contains substr.
+func (s *fakeUploadServer) called(substr string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.calls {
+		if strings.Contains(c, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// abandoned reports whether the asset was deleted and its job given status.
+func (s *fakeUploadServer) abandoned(status string) bool {
+	if !s.called("DELETE /assets/v1/assets/") {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, c := range s.calls {
+		if strings.HasPrefix(c, "PATCH /jobs/v1/jobs/") && strings.Contains(string(s.bodies[i]), `"`+status+`"`) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIClient_UploadFileSinglePart(t *testing.T) {
+	s := newFakeUploadServer(t)
+	client := newTestClient(t, s.URL)
+
+	content := []byte("<html><body>not really a video</body></html>")
+	assetID, err := client.UploadFile(context.Background(), bytes.NewReader(content), int64(len(content)), UploadOptions{
+		CollectionID: "col-1",
+		FileName:     "/tmp/lecture one.mp4",
+	})
+	if err != nil {
+		t.Fatalf("UploadFile() got error %v", err)
+	}
+	if assetID != "id-assets-v1-assets" {
+		t.Errorf("UploadFile() got asset ID %q; wanted the ID of the created asset", assetID)
+	}
+	if len(s.parts) != 1 {
+		t.Fatalf("UploadFile() made %d storage requests; wanted 1", len(s.parts))
+	}
+	part := s.parts[0]
+	sum := sha1.Sum(content)
+	wantBody := append(append([]byte{}, content...), hex.EncodeToString(sum[:])...)
+	if !bytes.Equal(part.body, wantBody) {
+		t.Errorf("UploadFile() uploaded %q; wanted %q", part.body, wantBody)
+	}
+	if got := part.header.Get("X-Bz-Content-Sha1"); got != "hex_digits_at_end" {
+		t.Errorf("UploadFile() sent X-Bz-Content-Sha1 %q; wanted hex_digits_at_end", got)
+	}
+	if got := part.header.Get("X-Bz-File-Name"); got != "lecture%20one.mp4" {
+		t.Errorf("UploadFile() sent X-Bz-File-Name %q; wanted %q", got, "lecture%20one.mp4")
+	}
+	if got := part.header.Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Errorf("UploadFile() sent Content-Type %q; wanted the detected text/html", got)
+	}
+	for _, want := range []string{"POST /assets/v1/assets", "PATCH /files/v1/assets/id-assets-v1-assets/files/", "PATCH /jobs/v1/jobs/"} {
+		if !s.called(want) {
+			t.Errorf("UploadFile() never called %q; calls were %v", want, s.calls)
+		}
+	}
+	if s.called("multipart") {
+		t.Errorf("UploadFile() of a small file used multipart: %v", s.calls)
+	}
+}
+
+// sizeLimitedReaderAt fails reads larger than limit, to check that a file is
+// streamed rather than read whole.
+type sizeLimitedReaderAt struct {
+	r     io.ReaderAt
+	limit int
+}
+
+func (s sizeLimitedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) > s.limit {
+		return 0, fmt.Errorf("read of %d bytes exceeds %d", len(p), s.limit)
+	}
+	return s.r.ReadAt(p, off)
+}
+
+func TestIClient_UploadFileStreams(t *testing.T) {
+	s := newFakeUploadServer(t)
+	client := newTestClient(t, s.URL)
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1<<16) // 1MB
+	r := sizeLimitedReaderAt{r: bytes.NewReader(content), limit: 64 << 10}
+	if _, err := client.UploadFile(context.Background(), r, int64(len(content)), UploadOptions{CollectionID: "c", FileName: "f"}); err != nil {
+		t.Fatalf("UploadFile() got error %v", err)
+	}
+	sum := sha1.Sum(content)
+	if got := s.parts[0].body; !bytes.Equal(got[:len(content)], content) || string(got[len(content):]) != hex.EncodeToString(sum[:]) {
+		t.Errorf("UploadFile() uploaded %d bytes that don't match the content and its SHA1", len(got))
+	}
+}
+
+func TestIClient_UploadFileStorageError(t *testing.T) {
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/storage/upload" {
+			http.Error(w, `{"code":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"id":"x","upload_url":"http://` + r.Host + `/storage/upload"}`))
+	})
+
+	_, err := client.UploadFile(context.Background(), strings.NewReader("data"), 4, UploadOptions{CollectionID: "c", FileName: "f"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("UploadFile() got %v; wanted the storage provider's 401", err)
+	}
+}
+
+func TestIClient_UploadFileIgnoresClientTimeout(t *testing.T) {
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/storage/upload" {
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`{"id":"x","upload_url":"http://` + r.Host + `/storage/upload"}`))
+	}, WithTimeout(100*time.Millisecond))
+
+	if _, err := client.UploadFile(context.Background(), strings.NewReader("data"), 4, UploadOptions{CollectionID: "c", FileName: "f"}); err != nil {
+		t.Errorf("UploadFile() with a storage request slower than the client timeout got error %v", err)
+	}
+}
+
+func TestIClient_UploadFileNeedsCollectionAndName(t *testing.T) {
+	client := newTestClient(t, "http://127.0.0.1:0")
+	if _, err := client.UploadFile(context.Background(), strings.NewReader(""), 0, UploadOptions{}); err == nil {
+		t.Errorf("UploadFile() without options got nil error")
+	}
+}
+
+func TestIClient_UploadFileMultipart(t *testing.T) {
+	s := newFakeUploadServer(t)
+	s.slowPart, s.others = 1, 3
+	client := newTestClient(t, s.URL)
+
+	size := 3*MinPartSize + 12345
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	_, err := client.UploadFile(context.Background(), bytes.NewReader(content), int64(size), UploadOptions{
+		CollectionID: "c",
+		FileName:     "f",
+		PartSize:     MinPartSize,
+		Concurrency:  3,
+	})
+	if err != nil {
+		t.Fatalf("UploadFile() got error %v", err)
+	}
+
+	if len(s.parts) != 4 {
+		t.Fatalf("UploadFile() uploaded %d parts; wanted 4", len(s.parts))
+	}
+	if s.parts[0].header.Get("X-Bz-Part-Number") == "1" {
+		t.Errorf("part 1 finished first; wanted it delayed behind the others")
+	}
+	var wantShas []string
+	for i := 0; i < 4; i++ {
+		start, end := i*MinPartSize, (i+1)*MinPartSize
+		if end > size {
+			end = size
+		}
+		sum := sha1.Sum(content[start:end])
+		wantShas = append(wantShas, hex.EncodeToString(sum[:]))
+		for _, p := range s.parts {
+			if p.header.Get("X-Bz-Part-Number") != strconv.Itoa(i+1) {
+				continue
+			}
+			if !bytes.Equal(p.body, content[start:end]) {
+				t.Errorf("part %d has the wrong content", i+1)
+			}
+			if got := p.header.Get("X-Bz-Content-Sha1"); got != wantShas[i] {
+				t.Errorf("part %d sent X-Bz-Content-Sha1 %s; wanted %s", i+1, got, wantShas[i])
+			}
+		}
+	}
+
+	var finish struct {
+		Sha1List []string `json:"sha1_list"`
+	}
+	partURLs := 0
+	for i, c := range s.calls {
+		if strings.HasSuffix(c, "/multipart/b2/finish/") {
+			json.Unmarshal(s.bodies[i], &finish)
+		}
+		if strings.HasSuffix(c, "/multipart/b2/url/") {
+			partURLs++
+		}
+	}
+	if strings.Join(finish.Sha1List, ",") != strings.Join(wantShas, ",") {
+		t.Errorf("UploadFile() finished with sha1_list %v; wanted %v", finish.Sha1List, wantShas)
+	}
+	if partURLs != 2 {
+		t.Errorf("UploadFile() requested %d extra part URLs; wanted 2 for 3 workers", partURLs)
+	}
+}
+
+func TestIClient_UploadFileMultipartFailure(t *testing.T) {
+	s := newFakeUploadServer(t)
+	s.failPart = 2
+	client := newTestClient(t, s.URL)
+
+	size := int64(2*MinPartSize + 1)
+	_, err := client.UploadFile(context.Background(), bytes.NewReader(make([]byte, size)), size, UploadOptions{
+		CollectionID: "c",
+		FileName:     "f",
+		PartSize:     MinPartSize,
+	})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !strings.Contains(err.Error(), "part 2") {
+		t.Errorf("UploadFile() got %v; wanted the failure of part 2", err)
+	}
+	if s.called("finish") {
+		t.Errorf("UploadFile() finished a failed upload")
+	}
+	if !s.abandoned("FAILED") {
+		t.Errorf("UploadFile() got calls %v; wanted the asset deleted and the job failed", s.calls)
+	}
+}
+
+func TestIClient_UploadFileMultipartShaMismatch(t *testing.T) {
+	for _, badSha := range []string{"0000000000000000000000000000000000000000", ""} {
+		s := newFakeUploadServer(t)
+		s.shaPart, s.badSha = 2, badSha
+		client := newTestClient(t, s.URL)
+
+		size := int64(2*MinPartSize + 1)
+		_, err := client.UploadFile(context.Background(), bytes.NewReader(make([]byte, size)), size, UploadOptions{
+			CollectionID: "c",
+			FileName:     "f",
+			PartSize:     MinPartSize,
+		})
+		if err == nil || !strings.Contains(err.Error(), "part 2") {
+			t.Errorf("UploadFile() with contentSha1 %q got %v; wanted the failure of part 2", badSha, err)
+		}
+		if s.called("finish") {
+			t.Errorf("UploadFile() with contentSha1 %q finished the upload", badSha)
+		}
+	}
+}
+
+func TestIClient_UploadFileSetupFailure(t *testing.T) {
+	s := newFakeUploadServer(t)
+	s.failCall = "POST /jobs/v1/jobs"
+	client := newTestClient(t, s.URL)
+
+	content := []byte("data")
+	if _, err := client.UploadFile(context.Background(), bytes.NewReader(content), int64(len(content)), UploadOptions{CollectionID: "c", FileName: "f"}); err == nil {
+		t.Fatalf("UploadFile() got nil error; wanted the job failure")
+	}
+	if !s.called("DELETE /assets/v1/assets/") {
+		t.Errorf("UploadFile() got calls %v; wanted the asset deleted", s.calls)
+	}
+	if len(s.parts) != 0 {
+		t.Errorf("UploadFile() sent %d parts after failing to set up the upload", len(s.parts))
+	}
+}
+
+func TestIClient_UploadFileFinishFailure(t *testing.T) {
+	s := newFakeUploadServer(t)
+	s.failCall = "multipart/b2/finish"
+	client := newTestClient(t, s.URL)
+
+	size := int64(MinPartSize + 1)
+	if _, err := client.UploadFile(context.Background(), bytes.NewReader(make([]byte, size)), size, UploadOptions{CollectionID: "c", FileName: "f", PartSize: MinPartSize}); err == nil {
+		t.Fatalf("UploadFile() got nil error; wanted the multipart finish failure")
+	}
+	if !s.abandoned("FAILED") {
+		t.Errorf("UploadFile() got calls %v; wanted the asset deleted and the job failed", s.calls)
+	}
+}
+
+func TestIClient_UploadFileKeepsCompleteFile(t *testing.T) {
+	s := newFakeUploadServer(t)
+	s.failCall = "keyframes"
+	client := newTestClient(t, s.URL)
+
+	content := []byte("data")
+	assetID, err := client.UploadFile(context.Background(), bytes.NewReader(content), int64(len(content)), UploadOptions{CollectionID: "c", FileName: "f"})
+	if err == nil {
+		t.Fatalf("UploadFile() got nil error; wanted the keyframe failure")
+	}
+	if assetID != "id-assets-v1-assets" {
+		t.Errorf("UploadFile() got asset ID %q with its error; wanted the ID of the kept asset", assetID)
+	}
+	if s.called("DELETE /assets/v1/assets/") {
+		t.Errorf("UploadFile() deleted an asset whose file was complete")
+	}
+}
+
+func TestUploadOptions_PartSize(t *testing.T) {
+	tests := []struct {
+		partSize, size, want int64
+		wantErr              bool
+	}{
+		{0, 1, MULTIPART_FILESIZE_THRESHOLD, false},
+		{MinPartSize, 1, MinPartSize, false},
+		{MinPartSize - 1, 1, 0, true},
+		{MaxPartSize + 1, 1, 0, true},
+		{MinPartSize, MinPartSize * MaxParts * 2, MinPartSize * 2, false},
+		{0, MaxPartSize*MaxParts + 1, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := UploadOptions{PartSize: tt.partSize}.partSize(tt.size)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("partSize(%d) with PartSize %d got %d, %v; wanted %d, error %t", tt.size, tt.partSize, got, err, tt.want, tt.wantErr)
+		}
+	}
+}